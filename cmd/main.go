@@ -1,44 +1,48 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"regexp"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/spf13/cobra"
-)
-
-// Club represents a cycling club
-type Club struct {
-	ClubName string `json:"clubName"`
-	ClubURL  string `json:"clubUrl"`
-	State    string `json:"state"`
-	LastSeen string `json:"lastSeen"`
-	Source   string `json:"source"`
-}
 
-// Event represents a cycling event
-type Event struct {
-	EventName string `json:"eventName"`
-	EventDate string `json:"eventDate"`
-	ClubName  string `json:"clubName"`
-	State     string `json:"state"`
-	EventURL  string `json:"eventUrl"`
-	Source    string `json:"source"`
-	Category  string `json:"category"`
-}
+	"racecalendar/pkg/buncheur"
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/entryboss"
+	"racecalendar/pkg/feed"
+	"racecalendar/pkg/ics"
+	"racecalendar/pkg/ratelimit"
+	"racecalendar/pkg/retry"
+	"racecalendar/pkg/server"
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "racecalendar",
 	Short: "Cycling Event Discovery Tool - scrape events from Australian clubs",
 	Long:  `A CLI tool to scrape cycling events from EntryBoss and Buncheur for Australian clubs and generate static data files.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newHTTPClient()
+		if err != nil {
+			return err
+		}
+		httpClient = client
+		limiter = ratelimit.NewPerHost(rateFlag, burstFlag)
+		return nil
+	},
 }
 
 var updateClubsCmd = &cobra.Command{
@@ -46,22 +50,50 @@ var updateClubsCmd = &cobra.Command{
 	Short: "Update the list of all Australian cycling clubs",
 	Long:  `Scrape EntryBoss to find all Australian cycling clubs from all states and save them to clubs.json`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := updateClubs(); err != nil {
+		if err := newUpdater().UpdateClubs(cmd.Context(), newEntryBoss()); err != nil {
 			log.Fatalf("Failed to update clubs: %v", err)
 		}
 		fmt.Println("Successfully updated clubs.json")
 	},
 }
 
-var stateFlag string
+var (
+	stateFlag         string
+	concurrencyFlag   int
+	detailsFlag       bool
+	detailsCacheFlag  string
+	detailsMaxAgeFlag time.Duration
+	detailsCache      *entryboss.DetailsCache
+	guard             = calendar.DefaultGuard()
+	changesFlag       bool
+	changesMDFlag     string
+	explainFlag       bool
+	filters           *calendar.Filters
+)
 
 var updateEventsCmd = &cobra.Command{
 	Use:   "update-events",
 	Short: "Update events from clubs (all states by default, or specific state with --state flag)",
 	Long:  `Read clubs.json and scrape events. If no state specified, processes all states. Use --state to process a specific state only.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		state := strings.ToUpper(stateFlag)
-		if err := updateEvents(state); err != nil {
+		if detailsFlag && detailsCacheFlag != "" {
+			cache, err := entryboss.OpenDetailsCache(detailsCacheFlag, detailsMaxAgeFlag)
+			if err != nil {
+				log.Fatalf("Failed to open race details cache: %v", err)
+			}
+			detailsCache = cache
+		}
+
+		updater, closeMarkdown := newEventsUpdater()
+		err := updater.UpdateEvents(cmd.Context(), newEntryBoss(), statesToProcess())
+		closeMarkdown()
+
+		if detailsCache != nil {
+			if err := detailsCache.Save(); err != nil {
+				log.Printf("Warning: failed to save race details cache: %v", err)
+			}
+		}
+		if err != nil {
 			log.Fatalf("Failed to update events: %v", err)
 		}
 	},
@@ -72,918 +104,564 @@ var updateBuncheurCmd = &cobra.Command{
 	Short: "Update events from Buncheur (all states by default, or specific state with --state flag)",
 	Long:  `Fetch events from Buncheur API. If no state specified, processes all states. Use --state to process a specific state only.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		state := strings.ToUpper(stateFlag)
-		if err := updateBuncheur(state); err != nil {
+		updater, closeMarkdown := newEventsUpdater()
+		err := updater.UpdateEvents(cmd.Context(), newBuncheur(), statesToProcess())
+		closeMarkdown()
+		if err != nil {
 			log.Fatalf("Failed to update Buncheur events: %v", err)
 		}
 	},
 }
 
-var migrateCmd = &cobra.Command{
-	Use:   "migrate",
-	Short: "Add state field to existing clubs.json (assumes VIC)",
-	Long:  `Migrate existing clubs.json to add state field with default value "VIC"`,
+var icsDirFlag string
+
+var exportICSCmd = &cobra.Command{
+	Use:   "export-ics",
+	Short: "Write subscribable .ics calendars per state, club and discipline",
+	Long:  `Read the events files and write iCalendar files for each state, each club and each discipline into the published site, so riders can subscribe to them from Google or Apple Calendar.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := migrateData(); err != nil {
-			log.Fatalf("Failed to migrate data: %v", err)
+		exporter := &ics.Exporter{Store: calendar.NewStore("."), Dir: icsDirFlag, Log: logger}
+		if err := exporter.Export(statesToProcess(), time.Now()); err != nil {
+			log.Fatalf("Failed to export calendars: %v", err)
 		}
-		fmt.Println("Successfully migrated data")
 	},
 }
 
-func init() {
-	updateEventsCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to process (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, processes all states.")
-	updateBuncheurCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to process (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, processes all states.")
-
-	rootCmd.AddCommand(updateClubsCmd)
-	rootCmd.AddCommand(updateEventsCmd)
-	rootCmd.AddCommand(updateBuncheurCmd)
-	rootCmd.AddCommand(migrateCmd)
-}
-
-func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-}
+var (
+	feedsDirFlag    string
+	feedsPrevFlag   string
+	feedsMaxAgeFlag time.Duration
+)
 
-func updateClubs() error {
-	// Load existing clubs from clubs.json if it exists
-	var existingClubs []Club
-	if data, err := os.ReadFile("clubs.json"); err == nil {
-		if err := json.Unmarshal(data, &existingClubs); err != nil {
-			fmt.Printf("Warning: failed to parse existing clubs.json: %v\n", err)
+var exportFeedsCmd = &cobra.Command{
+	Use:   "export-feeds",
+	Short: "Write Atom feeds of newly listed events per state and club",
+	Long:  `Compare the events files with a previous version (a directory or a git revision, HEAD by default) and add the events that are new to Atom feeds for each state and club.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		states := statesToProcess()
+		previous, err := loadPreviousEvents(feedsPrevFlag, states)
+		if err != nil {
+			log.Fatalf("Failed to load previous events: %v", err)
 		}
-	}
-
-	// Create map using clubURL as key for fast lookup and deduplication
-	clubMap := make(map[string]Club)
-	for _, club := range existingClubs {
-		clubMap[club.ClubURL] = club
-	}
 
-	currentTime := time.Now().Format(time.RFC3339)
-
-	// Fetch the main EntryBoss page
-	resp, err := http.Get("https://entryboss.cc/")
-	if err != nil {
-		return fmt.Errorf("failed to fetch main page: %w", err)
-	}
-	defer resp.Body.Close()
+		generator := &feed.Generator{Store: calendar.NewStore("."), Dir: feedsDirFlag, MaxAge: feedsMaxAgeFlag, Log: logger}
+		if err := generator.Generate(states, previous, time.Now()); err != nil {
+			log.Fatalf("Failed to export feeds: %v", err)
+		}
+	},
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("non-200 status code: %d", resp.StatusCode)
-	}
+var serveAddrFlag string
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to parse HTML: %w", err)
-	}
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the website and a JSON API over the data files",
+	Long:  `Serve the site from the current directory, with /api/events and /api/clubs answering queries over clubs.json and the events files. The files are reloaded when they change on disk.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		srv := server.New(calendar.NewStore("."), ".")
+		srv.Log = logger
+		httpServer := &http.Server{Addr: serveAddrFlag, Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
 
-	// Define Australian states
-	states := []string{"ACT", "NSW", "NT", "QLD", "SA", "TAS", "VIC", "WA"}
-	scrapedClubs := make(map[string]Club)
-	var currentState string
-
-	// Parse dropdown menu for all state sections
-	doc.Find("li").Each(func(i int, s *goquery.Selection) {
-		// Check if this is a state header
-		if s.HasClass("dropdown-header") {
-			headerText := strings.ToUpper(strings.TrimSpace(s.Text()))
-			for _, state := range states {
-				if strings.Contains(headerText, state) {
-					currentState = state
-					fmt.Printf("Found %s section in dropdown\n", state)
-					return
-				}
-			}
-			// If we hit a non-state header, clear current state
-			currentState = ""
-		}
-
-		// If we're in a state section, look for club links
-		if currentState != "" {
-			s.Find("a[href*='/calendar/']").Each(func(j int, link *goquery.Selection) {
-				href, exists := link.Attr("href")
-				if !exists {
-					return
-				}
-
-				clubName := strings.TrimSpace(link.Text())
-				if clubName != "" {
-					fullURL := "https://entryboss.cc" + href
-					scrapedClubs[fullURL] = Club{
-						ClubName: clubName,
-						ClubURL:  fullURL,
-						State:    currentState,
-						LastSeen: currentTime,
-						Source:   "EntryBoss",
-					}
-					fmt.Printf("Found %s club: %s -> %s\n", currentState, clubName, fullURL)
-				}
-			})
-		}
-	})
-
-	// Merge scraped clubs with existing clubs
-	newClubsCount := 0
-	updatedClubsCount := 0
-	stateStats := make(map[string]int)
-	var newClubNames []string
-
-	for clubURL, scrapedClub := range scrapedClubs {
-		if existingClub, exists := clubMap[clubURL]; exists {
-			// Update existing club with new information
-			existingClub.ClubName = scrapedClub.ClubName
-			existingClub.State = scrapedClub.State
-			existingClub.LastSeen = currentTime
-			// Preserve source if already set, otherwise set to EntryBoss
-			if existingClub.Source == "" {
-				existingClub.Source = "EntryBoss"
-			}
-			clubMap[clubURL] = existingClub
-			updatedClubsCount++
-		} else {
-			// Add new club
-			clubMap[clubURL] = scrapedClub
-			newClubsCount++
-			newClubNames = append(newClubNames, fmt.Sprintf("%s (%s)", scrapedClub.ClubName, scrapedClub.State))
-		}
-		stateStats[scrapedClub.State]++
-	}
+		go func() {
+			<-cmd.Context().Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(shutdown)
+		}()
 
-	// Handle migration: set lastSeen and source for existing clubs that don't have them
-	migrationCount := 0
-	for clubURL, club := range clubMap {
-		needsUpdate := false
-		if club.LastSeen == "" {
-			club.LastSeen = currentTime
-			needsUpdate = true
-		}
-		if club.Source == "" {
-			club.Source = "EntryBoss"
-			needsUpdate = true
+		fmt.Printf("Serving on http://localhost%s\n", serveAddrFlag)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
 		}
-		if needsUpdate {
-			clubMap[clubURL] = club
-			migrationCount++
-		}
-	}
+	},
+}
 
-	// Convert map to slice and sort
-	var clubList []Club
-	for _, club := range clubMap {
-		clubList = append(clubList, club)
-	}
+var diffFormatFlag string
 
-	sort.Slice(clubList, func(i, j int) bool {
-		if clubList[i].State != clubList[j].State {
-			return clubList[i].State < clubList[j].State
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-dir|git-rev> <new-dir>",
+	Short: "Report the events and clubs added, removed and changed between two snapshots",
+	Long:  `Compare the events files and clubs.json in two directories or git revisions, e.g. "diff HEAD~7 ." for the last week of updates, and report what was added, removed and changed as text, Markdown or JSON.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Not statesToProcess, whose message would spoil JSON output
+		states := calendar.States
+		if stateFlag != "" {
+			states = []string{strings.ToUpper(stateFlag)}
 		}
-		return clubList[i].ClubName < clubList[j].ClubName
-	})
 
-	// Write to clubs.json
-	data, err := json.MarshalIndent(clubList, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal clubs: %w", err)
-	}
-
-	if err := os.WriteFile("clubs.json", data, 0644); err != nil {
-		return fmt.Errorf("failed to write clubs.json: %w", err)
-	}
-
-	fmt.Printf("\nClub update summary:\n")
-	fmt.Printf("  Total clubs: %d\n", len(clubList))
-	fmt.Printf("  New clubs found: %d\n", newClubsCount)
-	if newClubsCount > 0 {
-		sort.Strings(newClubNames)
-		for _, name := range newClubNames {
-			fmt.Printf("    - %s\n", name)
+		diff, err := diffSnapshots(args[0], args[1], states, time.Now())
+		if err != nil {
+			log.Fatalf("Failed to compare %s and %s: %v", args[0], args[1], err)
 		}
-	}
-	fmt.Printf("  Existing clubs updated: %d\n", updatedClubsCount)
-	if migrationCount > 0 {
-		fmt.Printf("  Clubs migrated (added lastSeen): %d\n", migrationCount)
-	}
-	fmt.Printf("  Clubs preserved from previous runs: %d\n", len(clubList)-len(scrapedClubs))
 
-	fmt.Printf("\nClubs by state:\n")
-	for _, state := range states {
-		if count, exists := stateStats[state]; exists {
-			fmt.Printf("  %s: %d clubs\n", state, count)
+		switch diffFormatFlag {
+		case "text":
+			err = diff.WriteText(os.Stdout)
+		case "markdown":
+			err = diff.WriteMarkdown(os.Stdout)
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(diff)
+		default:
+			log.Fatalf("Unknown --format %q: use text, markdown or json", diffFormatFlag)
 		}
-	}
-
-	return nil
+		if err != nil {
+			log.Fatalf("Failed to write diff: %v", err)
+		}
+	},
 }
 
-func updateEvents(state string) error {
-	// Determine which states to process
-	var statesToProcess []string
-	if state == "" {
-		// Process all states
-		statesToProcess = []string{"ACT", "NSW", "NT", "QLD", "SA", "TAS", "VIC", "WA"}
-		fmt.Println("No state specified - processing all states...")
-	} else {
-		// Process single state
-		statesToProcess = []string{state}
-	}
+var mergeClubsDryRunFlag bool
 
-	// Read all clubs from clubs.json
-	data, err := os.ReadFile("clubs.json")
-	if err != nil {
-		return fmt.Errorf("failed to read clubs.json: %w", err)
-	}
-
-	var allClubs []Club
-	if err := json.Unmarshal(data, &allClubs); err != nil {
-		return fmt.Errorf("failed to unmarshal clubs.json: %w", err)
-	}
-
-	// Track statistics across all states
-	totalEvents := 0
-	stateResults := make(map[string]int)
+var mergeClubsCmd = &cobra.Command{
+	Use:   "merge-clubs [club-id...]",
+	Short: "Fold duplicate clubs in clubs.json together",
+	Long:  `Fold clubs that are the same club into one record, keeping the first club's ID and gaining the others' source identifiers and names as aliases. With IDs, folds those clubs into the first; without, folds every group of clubs in a state with the same name once normalised or through club-aliases.json.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := calendar.NewStore(".")
+		clubs, err := store.LoadClubs()
+		if err != nil {
+			log.Fatalf("Failed to load clubs: %v", err)
+		}
+		aliases, err := store.LoadAliases()
+		if err != nil {
+			log.Fatalf("Failed to load aliases: %v", err)
+		}
+		registry := calendar.NewRegistry(clubs, aliases)
 
-	// Process each state
-	for stateIndex, stateCode := range statesToProcess {
-		if len(statesToProcess) > 1 {
-			fmt.Printf("\n=== Processing %s (%d/%d) ===\n", stateCode, stateIndex+1, len(statesToProcess))
+		groups := [][]string{args}
+		if len(args) == 0 {
+			groups = registry.Duplicates()
+		}
+		if len(groups) == 0 {
+			fmt.Println("No duplicate clubs found")
+			return
 		}
 
-		// Filter clubs by state
-		var stateClubs []Club
-		for _, club := range allClubs {
-			if club.State == stateCode {
-				stateClubs = append(stateClubs, club)
+		for _, ids := range groups {
+			if mergeClubsDryRunFlag {
+				fmt.Printf("Would merge %s\n", strings.Join(ids, ", "))
+				continue
+			}
+			club, err := registry.Fold(ids...)
+			if err != nil {
+				log.Fatalf("Failed to merge %s: %v", strings.Join(ids, ", "), err)
 			}
+			fmt.Printf("Merged %s into %s (%s)\n", strings.Join(ids[1:], ", "), club.ID, club.ClubName)
 		}
-
-		if len(stateClubs) == 0 {
-			fmt.Printf("No clubs found for state %s, skipping...\n", stateCode)
-			continue
+		if mergeClubsDryRunFlag {
+			return
 		}
 
-		fmt.Printf("Found %d clubs in %s\n", len(stateClubs), stateCode)
-
-		var stateEvents []Event
+		if err := store.SaveClubs(registry.Clubs()); err != nil {
+			log.Fatalf("Failed to save clubs: %v", err)
+		}
+	},
+}
 
-		for _, club := range stateClubs {
-			fmt.Printf("Scraping events for %s...\n", club.ClubName)
+var classifyCmd = &cobra.Command{
+	Use:   "classify",
+	Short: "Classify the stored events again by the current rules",
+	Long:  `Set the discipline of every stored event from classify-rules.json (or the built-in rules if there is none), without fetching anything. The update commands classify events as they go; run this after editing the rules.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := calendar.NewStore(".")
+		rules, err := store.LoadRules()
+		if err != nil {
+			log.Fatalf("Failed to load rules: %v", err)
+		}
 
-			events, err := scrapeClubEvents(club)
+		for _, state := range statesToProcess() {
+			events, err := store.LoadEvents(state)
 			if err != nil {
-				log.Printf("Failed to scrape events for %s: %v", club.ClubName, err)
+				log.Fatalf("Failed to load events: %v", err)
+			}
+			if len(events) == 0 {
 				continue
 			}
-
-			// Add state and source fields to each event
-			for i := range events {
-				events[i].State = club.State
-				events[i].Source = "EntryBoss"
+			rules.Classify(events)
+			if err := store.SaveEvents(state, events); err != nil {
+				log.Fatalf("Failed to save events: %v", err)
 			}
 
-			stateEvents = append(stateEvents, events...)
-
-			// Small delay to be respectful to the server
-			time.Sleep(1 * time.Second)
-		}
-
-		// Load existing events to merge
-		eventsFile := fmt.Sprintf("events-%s.json", strings.ToLower(stateCode))
-		var existingEvents []Event
-		if existingData, err := os.ReadFile(eventsFile); err == nil {
-			if err := json.Unmarshal(existingData, &existingEvents); err == nil {
-				// Filter out existing EntryBoss events to replace them with fresh ones
-				var nonEntryBossEvents []Event
-				for _, e := range existingEvents {
-					if e.Source != "EntryBoss" {
-						nonEntryBossEvents = append(nonEntryBossEvents, e)
-					}
-				}
-				stateEvents = append(stateEvents, nonEntryBossEvents...)
+			counts := make(map[string]int)
+			for _, e := range events {
+				counts[e.Discipline]++
 			}
+			disciplines := make([]string, 0, len(counts))
+			for discipline, n := range counts {
+				disciplines = append(disciplines, fmt.Sprintf("%s %d", discipline, n))
+			}
+			sort.Strings(disciplines)
+			fmt.Printf("%s: %s\n", state, strings.Join(disciplines, ", "))
 		}
+	},
+}
 
-		// Sort events by date
-		sort.Slice(stateEvents, func(i, j int) bool {
-			return stateEvents[i].EventDate < stateEvents[j].EventDate
-		})
-
-		// Write to state-specific events file
-		eventData, err := json.MarshalIndent(stateEvents, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal events for %s: %w", stateCode, err)
-		}
-
-		if err := os.WriteFile(eventsFile, eventData, 0644); err != nil {
-			return fmt.Errorf("failed to write %s: %w", eventsFile, err)
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Add state field to existing clubs.json (assumes VIC)",
+	Long:  `Migrate existing clubs.json to add state field with default value "VIC"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := migrateData(); err != nil {
+			log.Fatalf("Failed to migrate data: %v", err)
 		}
+		fmt.Println("Successfully migrated data")
+	},
+}
 
-		fmt.Printf("Successfully scraped %d events from %d clubs in %s\n", len(stateEvents), len(stateClubs), stateCode)
+var (
+	entryBossURLFlag string
+	buncheurURLFlag  string
+	timeoutFlag      time.Duration
+	proxyFlag        string
+	rateFlag         float64
+	burstFlag        int
+	retryPolicy      = retry.DefaultPolicy()
+)
 
-		totalEvents += len(stateEvents)
-		stateResults[stateCode] = len(stateEvents)
+// httpClient and limiter are shared by every source; they are built from the
+// HTTP flags before any command runs.
+var (
+	httpClient *http.Client
+	limiter    *ratelimit.PerHost
+)
 
-		// Delay between states when processing multiple
-		if len(statesToProcess) > 1 && stateIndex < len(statesToProcess)-1 {
-			fmt.Println("Pausing before next state...")
-			time.Sleep(2 * time.Second)
-		}
-	}
+func init() {
+	rootCmd.PersistentFlags().StringVar(&entryBossURLFlag, "entryboss-url", envOr("RACECALENDAR_ENTRYBOSS_URL", entryboss.DefaultBaseURL), "EntryBoss base URL (env RACECALENDAR_ENTRYBOSS_URL)")
+	rootCmd.PersistentFlags().StringVar(&buncheurURLFlag, "buncheur-url", envOr("RACECALENDAR_BUNCHEUR_URL", buncheur.DefaultBaseURL), "Buncheur base URL (env RACECALENDAR_BUNCHEUR_URL)")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 30*time.Second, "Timeout for each HTTP request, including reading the body")
+	rootCmd.PersistentFlags().StringVar(&proxyFlag, "proxy", os.Getenv("RACECALENDAR_PROXY"), "HTTP proxy URL (env RACECALENDAR_PROXY; defaults to HTTP_PROXY/HTTPS_PROXY)")
+	rootCmd.PersistentFlags().Float64Var(&rateFlag, "rate", 1, "Maximum requests per second to each host (0 for no limit)")
+	rootCmd.PersistentFlags().IntVar(&burstFlag, "burst", 1, "Requests allowed to each host in a burst before --rate applies")
+	rootCmd.PersistentFlags().IntVar(&retryPolicy.Retries, "retries", retryPolicy.Retries, "Times to retry a request after a network error, 429 or 5xx response")
+	rootCmd.PersistentFlags().DurationVar(&retryPolicy.BaseDelay, "retry-delay", retryPolicy.BaseDelay, "Backoff before the first retry; doubles for each retry, with jitter")
+	rootCmd.PersistentFlags().DurationVar(&retryPolicy.MaxDelay, "retry-max-delay", retryPolicy.MaxDelay, "Longest backoff, and longest Retry-After that will be waited for")
 
-	// Print summary if multiple states were processed
-	if len(statesToProcess) > 1 {
-		fmt.Printf("\n=== Summary ===\n")
-		fmt.Printf("Total events scraped: %d\n", totalEvents)
-		fmt.Println("\nEvents by state:")
-		for _, stateCode := range statesToProcess {
-			if count, exists := stateResults[stateCode]; exists {
-				fmt.Printf("  %s: %d events\n", stateCode, count)
-			}
-		}
+	updateEventsCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to process (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, processes all states.")
+	updateEventsCmd.Flags().IntVarP(&concurrencyFlag, "concurrency", "c", 4, "Number of club pages to scrape at once")
+	updateEventsCmd.Flags().BoolVar(&detailsFlag, "details", false, "Also fetch each race page for start time, venue, entries close date, grades and status")
+	updateEventsCmd.Flags().StringVar(&detailsCacheFlag, "details-cache", ".cache/entryboss-races.json", "Race details cache file (empty to disable caching)")
+	updateEventsCmd.Flags().DurationVar(&detailsMaxAgeFlag, "details-max-age", 7*24*time.Hour, "Reuse cached race details this long while the club calendar lists the race unchanged")
+	updateBuncheurCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to process (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, processes all states.")
+	classifyCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to classify (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, classifies all states.")
+	exportICSCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to export (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, exports all states.")
+	exportICSCmd.Flags().StringVar(&icsDirFlag, "dir", ics.DefaultDir, "Directory to write the .ics files to")
+	serveCmd.Flags().StringVar(&serveAddrFlag, "addr", ":8000", "Address to listen on")
+	exportFeedsCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to export (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, exports all states.")
+	exportFeedsCmd.Flags().StringVar(&feedsDirFlag, "dir", "feeds", "Directory to write the feeds to")
+	exportFeedsCmd.Flags().StringVar(&feedsPrevFlag, "previous", "HEAD", "Directory or git revision holding the events files before the update")
+	exportFeedsCmd.Flags().DurationVar(&feedsMaxAgeFlag, "max-age", 30*24*time.Hour, "How long a new event stays in the feeds")
+
+	mergeClubsCmd.Flags().BoolVar(&mergeClubsDryRunFlag, "dry-run", false, "Report the clubs that would be merged without changing clubs.json")
+	diffCmd.Flags().StringVarP(&stateFlag, "state", "s", "", "State code to compare (VIC, NSW, QLD, SA, WA, TAS, ACT, NT). If not specified, compares all states.")
+	diffCmd.Flags().StringVar(&diffFormatFlag, "format", "text", "Output format: text, markdown or json")
+
+	for _, cmd := range []*cobra.Command{updateClubsCmd, updateEventsCmd, updateBuncheurCmd} {
+		cmd.Flags().Float64Var(&guard.MaxDropPercent, "max-drop", guard.MaxDropPercent, "Refuse to write a file if a source's upcoming events or clubs drop by more than this percentage")
+		cmd.Flags().IntVar(&guard.BusyClubEvents, "busy-club", guard.BusyClubEvents, "Refuse to write a file if a club with at least this many upcoming events drops to none")
+		cmd.Flags().BoolVar(&guard.Force, "force", false, "Write files even if the shrinkage checks fail")
+	}
+	for _, cmd := range []*cobra.Command{updateEventsCmd, updateBuncheurCmd} {
+		cmd.Flags().BoolVar(&changesFlag, "changes", true, "Record the events added, removed, moved and renamed by the run in changes/")
+		cmd.Flags().StringVar(&changesMDFlag, "changes-markdown", "", "Append the run's changes as Markdown to this file (- for stdout)")
+		cmd.Flags().BoolVar(&explainFlag, "explain", false, "Report each candidate event dropped by the event filters and the rule that dropped it")
 	}
 
-	return nil
+	rootCmd.AddCommand(updateClubsCmd)
+	rootCmd.AddCommand(updateEventsCmd)
+	rootCmd.AddCommand(updateBuncheurCmd)
+	rootCmd.AddCommand(exportICSCmd)
+	rootCmd.AddCommand(exportFeedsCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(mergeClubsCmd)
+	rootCmd.AddCommand(classifyCmd)
+	rootCmd.AddCommand(migrateCmd)
 }
 
-func migrateData() error {
-	// Read clubs.json
-	data, err := os.ReadFile("clubs.json")
-	if err != nil {
-		return fmt.Errorf("failed to read clubs.json: %w", err)
-	}
-
-	var clubs []Club
-	if err := json.Unmarshal(data, &clubs); err != nil {
-		return fmt.Errorf("failed to parse clubs.json: %w", err)
-	}
-
-	// Add state: "VIC" to all clubs that don't have it
-	modified := 0
-	for i := range clubs {
-		if clubs[i].State == "" {
-			clubs[i].State = "VIC"
-			modified++
-		}
-	}
-
-	// Write back to clubs.json
-	data, err = json.MarshalIndent(clubs, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal clubs: %w", err)
-	}
+func main() {
+	// Cancel in-flight scrapes on Ctrl+C or when a CI job is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := os.WriteFile("clubs.json", data, 0644); err != nil {
-		return fmt.Errorf("failed to write clubs.json: %w", err)
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	fmt.Printf("Successfully added state field to %d clubs\n", modified)
-
-	// Check if events.json exists and needs migration
-	if eventData, err := os.ReadFile("events.json"); err == nil {
-		var events []Event
-		if err := json.Unmarshal(eventData, &events); err != nil {
-			return fmt.Errorf("failed to parse events.json: %w", err)
-		}
-
-		// Add state: "VIC" to all events that don't have it
-		eventsModified := 0
-		for i := range events {
-			if events[i].State == "" {
-				events[i].State = "VIC"
-				eventsModified++
-			}
-		}
-
-		// Write to events-vic.json
-		eventData, err = json.MarshalIndent(events, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal events: %w", err)
-		}
-
-		if err := os.WriteFile("events-vic.json", eventData, 0644); err != nil {
-			return fmt.Errorf("failed to write events-vic.json: %w", err)
-		}
-
-		fmt.Printf("Successfully migrated %d events to events-vic.json\n", eventsModified)
-		fmt.Println("Note: Original events.json preserved. You may want to remove it after verifying the migration.")
-	}
+// logger prints progress to stdout, as the commands always have.
+var logger = log.New(os.Stdout, "", 0)
 
-	return nil
+func newUpdater() *calendar.Updater {
+	return &calendar.Updater{Store: calendar.NewStore("."), Log: logger, Guard: guard}
 }
 
-func scrapeClubEvents(club Club) ([]Event, error) {
-	resp, err := http.Get(club.ClubURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch club page: %w", err)
-	}
-	defer resp.Body.Close()
+// newEventsUpdater returns the updater for the update-events commands, with
+// the changelog options from the flags. The returned function closes the
+// Markdown file, if any.
+func newEventsUpdater() (*calendar.Updater, func()) {
+	updater := newUpdater()
+	updater.RecordChanges = changesFlag
+	updater.Filters = eventFilters()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("non-200 status code: %d", resp.StatusCode)
+	switch changesMDFlag {
+	case "":
+		return updater, func() {}
+	case "-":
+		updater.Markdown = os.Stdout
+		return updater, func() {}
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	f, err := os.OpenFile(changesMDFlag, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		log.Fatalf("Failed to open %s: %v", changesMDFlag, err)
 	}
-
-	var events []Event
-	now := time.Now()
-
-	// Method 1: Look for event links in standard format
-	doc.Find("a[href*='/races/']").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if !exists {
-			return
-		}
-
-		eventName := strings.TrimSpace(s.Text())
-		if eventName == "" {
-			return
-		}
-
-		// Skip obviously non-event links
-		if strings.ToLower(eventName) == "enter" ||
-			strings.ToLower(eventName) == "register" ||
-			strings.ToLower(eventName) == "sign up" ||
-			strings.ToLower(eventName) == "view" ||
-			strings.ToLower(eventName) == "details" ||
-			strings.Contains(strings.ToLower(eventName), "season pass") ||
-			strings.Contains(strings.ToLower(eventName), "volunteer") ||
-			strings.Contains(strings.ToLower(eventName), "replacement") ||
-			strings.Contains(strings.ToLower(eventName), "pre-order") ||
-			len(eventName) < 5 {
-			return
+	updater.Markdown = f
+	return updater, func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Warning: failed to write %s: %v", changesMDFlag, err)
 		}
-
-		// Try to extract date information from nearby elements
-		eventDate := extractEventDate(s)
-
-		// Only include future events
-		if eventDate != "" {
-			if parsedDate, err := time.Parse("2006-01-02T15:04:05Z", eventDate); err == nil {
-				if parsedDate.After(now.AddDate(0, 0, -1)) { // Include events from yesterday onwards
-					events = append(events, Event{
-						EventName: eventName,
-						EventDate: eventDate,
-						ClubName:  club.ClubName,
-						EventURL:  "https://entryboss.cc" + href,
-					})
-				}
-			}
-		}
-	})
-
-	// Method 2: Look for table-based event listings (like Northern Combine)
-	doc.Find("table tr, .fixture-row, .event-row").Each(func(i int, row *goquery.Selection) {
-		// Look for date patterns in the row
-		rowText := row.Text()
-		eventDate := parseDateFromText(rowText)
-
-		if eventDate != "" {
-			// Look for race links in this row
-			row.Find("a[href*='/races/']").Each(func(j int, link *goquery.Selection) {
-				href, exists := link.Attr("href")
-				if !exists {
-					return
-				}
-
-				eventName := strings.TrimSpace(link.Text())
-				if eventName == "" {
-					return
-				}
-
-				// Skip non-event entries
-				if strings.ToLower(eventName) == "enter" ||
-					strings.ToLower(eventName) == "register" ||
-					strings.ToLower(eventName) == "sign up" ||
-					strings.ToLower(eventName) == "view" ||
-					strings.ToLower(eventName) == "details" ||
-					strings.Contains(strings.ToLower(eventName), "season pass") ||
-					strings.Contains(strings.ToLower(eventName), "volunteer") ||
-					strings.Contains(strings.ToLower(eventName), "replacement") ||
-					strings.Contains(strings.ToLower(eventName), "pre-order") ||
-					len(eventName) < 5 {
-					return
-				}
-
-				// Check if this is a future event
-				if parsedDate, err := time.Parse("2006-01-02T15:04:05Z", eventDate); err == nil {
-					if parsedDate.After(now.AddDate(0, 0, -1)) {
-						events = append(events, Event{
-							EventName: eventName,
-							EventDate: eventDate,
-							ClubName:  club.ClubName,
-							EventURL:  "https://entryboss.cc" + href,
-						})
-					}
-				}
-			})
-		}
-	})
-
-	// Method 3: Look for events in "Upcoming" sections
-	doc.Find("h3, h4, .section-header").Each(func(i int, header *goquery.Selection) {
-		headerText := strings.ToLower(strings.TrimSpace(header.Text()))
-		if strings.Contains(headerText, "upcoming") || strings.Contains(headerText, "fixture") {
-			// Process the next few sibling elements for events
-			current := header.Next()
-			for j := 0; j < 10 && current.Length() > 0; j++ {
-				current.Find("a[href*='/races/']").Each(func(k int, link *goquery.Selection) {
-					href, exists := link.Attr("href")
-					if !exists {
-						return
-					}
-
-					eventName := strings.TrimSpace(link.Text())
-					if eventName == "" {
-						return
-					}
-
-					// Skip non-event entries
-					if strings.ToLower(eventName) == "enter" ||
-						strings.ToLower(eventName) == "register" ||
-						strings.ToLower(eventName) == "sign up" ||
-						strings.ToLower(eventName) == "view" ||
-						strings.ToLower(eventName) == "details" ||
-						strings.Contains(strings.ToLower(eventName), "season pass") ||
-						strings.Contains(strings.ToLower(eventName), "volunteer") ||
-						strings.Contains(strings.ToLower(eventName), "replacement") ||
-						strings.Contains(strings.ToLower(eventName), "pre-order") ||
-						len(eventName) < 5 {
-						return
-					}
-
-					eventDate := extractEventDate(link)
-					if eventDate != "" {
-						if parsedDate, err := time.Parse("2006-01-02T15:04:05Z", eventDate); err == nil {
-							if parsedDate.After(now.AddDate(0, 0, -1)) {
-								events = append(events, Event{
-									EventName: eventName,
-									EventDate: eventDate,
-									ClubName:  club.ClubName,
-									EventURL:  "https://entryboss.cc" + href,
-								})
-							}
-						}
-					}
-				})
-				current = current.Next()
-			}
-		}
-	})
-
-	// Remove duplicates based on event URL
-	uniqueEvents := make(map[string]Event)
-	for _, event := range events {
-		uniqueEvents[event.EventURL] = event
-	}
-
-	events = make([]Event, 0, len(uniqueEvents))
-	for _, event := range uniqueEvents {
-		events = append(events, event)
 	}
-
-	return events, nil
 }
 
-func extractEventDate(eventLink *goquery.Selection) string {
-	// Look for date patterns in the text content and nearby elements
-
-	// First, check the event link text itself
-	text := eventLink.Text()
-	if date := parseDateFromText(text); date != "" {
-		return date
-	}
-
-	// Check parent elements for date information
-	parent := eventLink.Parent()
-	for i := 0; i < 5; i++ {
-		parentText := strings.TrimSpace(parent.Text())
-		if date := parseDateFromText(parentText); date != "" {
-			return date
-		}
-
-		// Also check siblings of parent
-		parent.Siblings().Each(func(j int, sibling *goquery.Selection) {
-			if date := parseDateFromText(sibling.Text()); date != "" {
-				return
-			}
-		})
+func newEntryBoss() *entryboss.Source {
+	src := entryboss.New(httpClient)
+	src.BaseURL = strings.TrimSuffix(entryBossURLFlag, "/")
+	src.Limiter = limiter
+	src.Concurrency = concurrencyFlag
+	src.Retry = retryPolicy
+	src.Details = detailsFlag
+	src.DetailsCache = detailsCache
+	src.Log = logger
+	return src
+}
 
-		parent = parent.Parent()
-		if parent.Length() == 0 {
-			break
+// eventFilters loads event-filters.json, or the built-in filters, the first
+// time it is called, set to explain drops if --explain was given.
+func eventFilters() *calendar.Filters {
+	if filters == nil {
+		f, err := calendar.NewStore(".").LoadFilters()
+		if err != nil {
+			log.Fatalf("Failed to load event filters: %v", err)
 		}
+		f.Explain, f.Log = explainFlag, logger
+		filters = f
 	}
-
-	// Check previous and next siblings
-	eventLink.Prev().Each(func(i int, s *goquery.Selection) {
-		if date := parseDateFromText(s.Text()); date != "" {
-			return
-		}
-	})
-
-	eventLink.Next().Each(func(i int, s *goquery.Selection) {
-		if date := parseDateFromText(s.Text()); date != "" {
-			return
-		}
-	})
-
-	return ""
+	return filters
 }
 
-func parseDateFromText(text string) string {
-	// Common date patterns found on EntryBoss
-	datePatterns := []struct {
-		pattern string
-		layout  string
-	}{
-		{`(\w{3}),?\s+(\d{1,2})\s+(\w{3})\s+(\d{4})`, "Mon, 2 Jan 2006"},    // "Sat, 5 Jul 2025"
-		{`(\w+),?\s+(\d{1,2})\s+(\w+)\s+(\d{4})`, "Monday, 2 January 2006"}, // "Sunday, 13 July 2025"
-		{`(\d{1,2})\s+(\w{3})\s+(\d{4})`, "2 Jan 2006"},                     // "5 Jul 2025"
-		{`(\d{4})-(\d{2})-(\d{2})`, "2006-01-02"},                           // "2025-07-05"
-		{`(\w{3})\s+(\d{1,2})\s+(\w{3})\s+(\d{4})`, "Mon 2 Jan 2006"},       // "Sun 6 Jul 2025"
-	}
+func newBuncheur() *buncheur.Source {
+	src := buncheur.New(httpClient)
+	src.BaseURL = strings.TrimSuffix(buncheurURLFlag, "/")
+	src.Limiter = limiter
+	src.Retry = retryPolicy
+	src.Log = logger
+	return src
+}
 
-	for _, dp := range datePatterns {
-		re := regexp.MustCompile(dp.pattern)
-		if matches := re.FindStringSubmatch(text); len(matches) > 0 {
-			return convertToISO8601(matches[0], dp.layout)
+// newHTTPClient builds the client described by --timeout and --proxy.
+func newHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyFlag != "" {
+		proxyURL, err := url.Parse(proxyFlag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --proxy %q: %w", proxyFlag, err)
 		}
+		transport.Proxy = http.ProxyURL(proxyURL)
 	}
 
-	return ""
+	return &http.Client{Timeout: timeoutFlag, Transport: transport}, nil
 }
 
-func convertToISO8601(dateStr, layout string) string {
-	// Clean up the date string
-	dateStr = strings.TrimSpace(dateStr)
-
-	// Try to parse with the given layout
-	if parsedTime, err := time.Parse(layout, dateStr); err == nil {
-		return parsedTime.Format("2006-01-02T15:04:05Z")
+// envOr returns the environment variable key, or fallback if it is unset.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
+}
 
-	// Try common variations
-	layouts := []string{
-		"Mon, 2 Jan 2006",
-		"Monday, 2 January 2006",
-		"2 Jan 2006",
-		"2006-01-02",
-		"Mon 2 Jan 2006",
-		"2 January 2006",
-		"Jan 2, 2006",
-		"January 2, 2006",
+// statesToProcess returns the state given by --state, or every state.
+func statesToProcess() []string {
+	if stateFlag == "" {
+		fmt.Println("No state specified - processing all states...")
+		return calendar.States
 	}
+	return []string{strings.ToUpper(stateFlag)}
+}
 
-	for _, layout := range layouts {
-		if parsedTime, err := time.Parse(layout, dateStr); err == nil {
-			return parsedTime.Format("2006-01-02T15:04:05Z")
-		}
+// snapshotReader returns a function that reads a data file from ref, which
+// is either a directory or a git revision of the current repository. Files
+// that do not exist in ref give an error wrapping fs.ErrNotExist.
+func snapshotReader(ref string) (func(name string) ([]byte, error), error) {
+	if info, err := os.Stat(ref); err == nil && info.IsDir() {
+		return func(name string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(ref, name))
+		}, nil
 	}
 
-	// If all else fails, try to extract year/month/day with regex
-	if year, month, day := extractDateComponents(dateStr); year != 0 {
-		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
-		return date.Format("2006-01-02T15:04:05Z")
+	if err := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil {
+		return nil, fmt.Errorf("%q is neither a directory nor a git revision", ref)
 	}
-
-	return ""
+	return func(name string) ([]byte, error) {
+		// "./" makes git resolve name from the current directory rather than
+		// the repository root, as the data files live in the working directory.
+		data, err := exec.Command("git", "show", ref+":./"+filepath.ToSlash(name)).Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s is not in %s: %w", name, ref, fs.ErrNotExist)
+		}
+		return data, nil
+	}, nil
 }
 
-func extractDateComponents(text string) (year, month, day int) {
-	// Month name mapping
-	monthMap := map[string]int{
-		"jan": 1, "january": 1,
-		"feb": 2, "february": 2,
-		"mar": 3, "march": 3,
-		"apr": 4, "april": 4,
-		"may": 5,
-		"jun": 6, "june": 6,
-		"jul": 7, "july": 7,
-		"aug": 8, "august": 8,
-		"sep": 9, "september": 9,
-		"oct": 10, "october": 10,
-		"nov": 11, "november": 11,
-		"dec": 12, "december": 12,
+// loadPreviousEvents reads each state's events from ref, a directory or git
+// revision. States whose file does not exist in ref have no events.
+func loadPreviousEvents(ref string, states []string) (map[string][]calendar.Event, error) {
+	read, err := snapshotReader(ref)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract numbers and month names
-	re := regexp.MustCompile(`\b(\d{4})\b|\b(\d{1,2})\b|\b(\w+)\b`)
-	matches := re.FindAllString(strings.ToLower(text), -1)
-
-	var numbers []int
-	var monthName string
-
-	for _, match := range matches {
-		// Try to parse as number
-		var num int
-		if n, err := fmt.Sscanf(match, "%d", &num); n == 1 && err == nil {
-			if num >= 1900 && num <= 2100 {
-				// This is likely a year
-				year = num
-			} else if num >= 1 && num <= 31 {
-				// This is likely a day
-				numbers = append(numbers, num)
-			}
-		} else if _, exists := monthMap[match]; exists {
-			monthName = match
+	previous := make(map[string][]calendar.Event)
+	for _, state := range states {
+		name := calendar.EventsFile(state)
+		data, err := read(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", name, ref, err)
+		}
+		var events []calendar.Event
+		if err := json.Unmarshal(data, &events); err != nil {
+			return nil, fmt.Errorf("failed to parse %s from %s: %w", name, ref, err)
 		}
+		previous[state] = events
 	}
+	return previous, nil
+}
 
-	if monthName != "" {
-		month = monthMap[monthName]
+// loadPreviousClubs reads clubs.json from ref, a directory or git revision.
+// A missing file has no clubs.
+func loadPreviousClubs(ref string) ([]calendar.Club, error) {
+	read, err := snapshotReader(ref)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(numbers) > 0 {
-		day = numbers[0]
+	data, err := read(calendar.ClubsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
 	}
-
-	return
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %w", calendar.ClubsFile, ref, err)
+	}
+	var clubs []calendar.Club
+	if err := json.Unmarshal(data, &clubs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s from %s: %w", calendar.ClubsFile, ref, err)
+	}
+	return clubs, nil
 }
 
-func updateBuncheur(state string) error {
-	fmt.Printf("Fetching Buncheur events for state: %s\n", state)
-	
-	// Fetch events from Buncheur
-	url := "https://www.buncheur.com/events"
-	if state != "" {
-		url += "?state=" + state
-	}
+// diffSnapshots compares the events and clubs of states in two directories or
+// git revisions.
+func diffSnapshots(oldRef, newRef string, states []string, now time.Time) (calendar.Diff, error) {
+	var diff calendar.Diff
 
-	resp, err := http.Get(url)
+	oldEvents, err := loadPreviousEvents(oldRef, states)
 	if err != nil {
-		return fmt.Errorf("failed to fetch Buncheur events: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("non-200 status code from Buncheur: %d", resp.StatusCode)
+		return diff, err
 	}
-
-	var buncheurEvents []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&buncheurEvents); err != nil {
-		return fmt.Errorf("failed to decode Buncheur JSON: %w", err)
+	newEvents, err := loadPreviousEvents(newRef, states)
+	if err != nil {
+		return diff, err
 	}
-
-	fmt.Printf("Found %d events from Buncheur\n", len(buncheurEvents))
-
-	// Group events by state for processing
-	eventsByState := make(map[string][]Event)
-	scrapedClubsByState := make(map[string]map[string]Club)
-
-	for _, be := range buncheurEvents {
-		eventState, _ := be["state"].(string)
-		if eventState == "" {
-			continue
-		}
-		// If we're filtering by state, skip others
-		if state != "" && eventState != state {
-			continue
-		}
-
-		title, _ := be["title"].(string)
-		clubName, _ := be["club"].(string)
-		eventUrl, _ := be["url"].(string)
-		startDate, _ := be["start"].(string)
-		category, _ := be["item_category"].(string)
-
-		if title == "" || startDate == "" {
-			continue
-		}
-
-		// Normalize date to ISO8601
-		eventDate := startDate
-		if len(startDate) == 10 { // YYYY-MM-DD
-			eventDate = startDate + "T00:00:00Z"
-		}
-
-		fullUrl := "https://www.buncheur.com" + eventUrl
-		event := Event{
-			EventName: title,
-			EventDate: eventDate,
-			ClubName:  clubName,
-			State:     eventState,
-			EventURL:  fullUrl,
-			Source:    "Buncheur",
-			Category:  category,
-		}
-
-		eventsByState[eventState] = append(eventsByState[eventState], event)
-
-		// Collect club info
-		if clubName != "" {
-			if scrapedClubsByState[eventState] == nil {
-				scrapedClubsByState[eventState] = make(map[string]Club)
-			}
-			scrapedClubsByState[eventState][clubName] = Club{
-				ClubName: clubName,
-				ClubURL:  "https://www.buncheur.com" + eventUrl, // We don't have a direct club URL from this API, using event URL as a fallback/placeholder
-				State:    eventState,
-				LastSeen: time.Now().Format(time.RFC3339),
-				Source:   "Buncheur",
-			}
+	for _, state := range states {
+		changes := calendar.CompareEvents(oldEvents[state], newEvents[state], now)
+		changes.State = state
+		if !changes.Empty() {
+			diff.Events = append(diff.Events, changes)
 		}
 	}
 
-	// Update clubs.json
-	if err := syncBuncheurClubs(scrapedClubsByState); err != nil {
-		fmt.Printf("Warning: failed to sync clubs: %v\n", err)
+	oldClubs, err := loadPreviousClubs(oldRef)
+	if err != nil {
+		return diff, err
 	}
+	newClubs, err := loadPreviousClubs(newRef)
+	if err != nil {
+		return diff, err
+	}
+	diff.Clubs = calendar.CompareClubs(clubsIn(oldClubs, states), clubsIn(newClubs, states))
+	return diff, nil
+}
 
-	// Update each state's events file
-	for stateCode, newEvents := range eventsByState {
-		eventsFile := fmt.Sprintf("events-%s.json", strings.ToLower(stateCode))
-		var allEvents []Event
-
-		// Load existing
-		if data, err := os.ReadFile(eventsFile); err == nil {
-			var existingEvents []Event
-			if err := json.Unmarshal(data, &existingEvents); err == nil {
-				// Filter out old Buncheur events
-				for _, e := range existingEvents {
-					if e.Source != "Buncheur" {
-						allEvents = append(allEvents, e)
-					}
-				}
+// clubsIn returns the clubs in any of states.
+func clubsIn(clubs []calendar.Club, states []string) []calendar.Club {
+	var in []calendar.Club
+	for _, club := range clubs {
+		for _, state := range states {
+			if club.State == state {
+				in = append(in, club)
+				break
 			}
 		}
-
-		// Add new Buncheur events
-		allEvents = append(allEvents, newEvents...)
-
-		// Sort
-		sort.Slice(allEvents, func(i, j int) bool {
-			return allEvents[i].EventDate < allEvents[j].EventDate
-		})
-
-		// Save
-		data, err := json.MarshalIndent(allEvents, "", "  ")
-		if err != nil {
-			fmt.Printf("Error marshaling events for %s: %v\n", stateCode, err)
-			continue
-		}
-		if err := os.WriteFile(eventsFile, data, 0644); err != nil {
-			fmt.Printf("Error writing events file for %s: %v\n", stateCode, err)
-			continue
-		}
-		fmt.Printf("Updated %s with %d Buncheur events (Total: %d)\n", eventsFile, len(newEvents), len(allEvents))
 	}
-
-	return nil
+	return in
 }
 
-func syncBuncheurClubs(scrapedClubsByState map[string]map[string]Club) error {
-	// Read existing clubs
-	data, err := os.ReadFile("clubs.json")
+func migrateData() error {
+	store := calendar.NewStore(".")
+
+	clubs, err := store.LoadClubs()
 	if err != nil {
 		return err
 	}
-	var existingClubs []Club
-	if err := json.Unmarshal(data, &existingClubs); err != nil {
-		return err
-	}
 
-	clubMap := make(map[string]Club)
-	for _, c := range existingClubs {
-		clubMap[c.ClubName+c.State] = c
+	// Add state: "VIC" to all clubs that don't have it
+	modified := 0
+	for i := range clubs {
+		if clubs[i].State == "" {
+			clubs[i].State = "VIC"
+			modified++
+		}
 	}
 
-	addedCount := 0
-	for _, stateClubs := range scrapedClubsByState {
-		for clubName, scrapedClub := range stateClubs {
-			key := clubName + scrapedClub.State
-			if _, exists := clubMap[key]; !exists {
-				clubMap[key] = scrapedClub
-				addedCount++
-			}
-		}
+	if err := store.SaveClubs(clubs); err != nil {
+		return err
 	}
 
-	if addedCount == 0 {
+	fmt.Printf("Successfully added state field to %d clubs\n", modified)
+
+	// Check if events.json exists and needs migration
+	eventData, err := os.ReadFile("events.json")
+	if err != nil {
 		return nil
 	}
 
-	// Convert back to slice and sort
-	var newList []Club
-	for _, c := range clubMap {
-		newList = append(newList, c)
+	var events []calendar.Event
+	if err := json.Unmarshal(eventData, &events); err != nil {
+		return fmt.Errorf("failed to parse events.json: %w", err)
 	}
-	sort.Slice(newList, func(i, j int) bool {
-		if newList[i].State != newList[j].State {
-			return newList[i].State < newList[j].State
+
+	// Add state: "VIC" to all events that don't have it
+	eventsModified := 0
+	for i := range events {
+		if events[i].State == "" {
+			events[i].State = "VIC"
+			eventsModified++
 		}
-		return newList[i].ClubName < newList[j].ClubName
-	})
+	}
 
-	data, err = json.MarshalIndent(newList, "", "  ")
-	if err != nil {
+	if err := store.SaveEvents("VIC", events); err != nil {
 		return err
 	}
-	fmt.Printf("Added %d new clubs from Buncheur\n", addedCount)
-	return os.WriteFile("clubs.json", data, 0644)
+
+	fmt.Printf("Successfully migrated %d events to %s\n", eventsModified, calendar.EventsFile("VIC"))
+	fmt.Println("Note: Original events.json preserved. You may want to remove it after verifying the migration.")
+
+	return nil
 }
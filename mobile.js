@@ -30,6 +30,7 @@ const state = {
     currentView: 'calendar', // 'list' or 'calendar'
     hideBMXEvents: false,
     hideMTBEvents: false,
+    racesOnly: true, // Hide training, coaching, social and other non-race listings
     isFirstTime: true,
     currentDate: new Date(),
     selectedDate: null, // Track selected calendar date
@@ -112,6 +113,7 @@ function initializeElements() {
     elements.applyFiltersButton = document.getElementById('applyFiltersButton');
     elements.hideBMXCheckbox = document.getElementById('hideBMXCheckbox');
     elements.hideMTBCheckbox = document.getElementById('hideMTBCheckbox');
+    elements.racesOnlyCheckbox = document.getElementById('racesOnlyCheckbox');
     elements.clubSearchInput = document.getElementById('clubSearchInput');
     elements.clubFiltersList = document.getElementById('clubFiltersList');
     elements.clearAllClubsButton = document.getElementById('clearAllClubsButton');
@@ -155,6 +157,9 @@ function setupEventListeners() {
     elements.hideMTBCheckbox.addEventListener('change', (e) => {
         state.hideMTBEvents = e.target.checked;
     });
+    elements.racesOnlyCheckbox.addEventListener('change', (e) => {
+        state.racesOnly = e.target.checked;
+    });
     
     // Club search
     elements.clubSearchInput.addEventListener('input', filterClubList);
@@ -307,16 +312,20 @@ function loadStateSpecificPreferences() {
     const prefs = state.statePreferences[state.selectedState] || {
         selectedClubs: [],
         hideBMXEvents: false,
-        hideMTBEvents: false
+        hideMTBEvents: false,
+        racesOnly: true
     };
     
     state.selectedClubs = new Set(prefs.selectedClubs);
     state.hideBMXEvents = prefs.hideBMXEvents;
     state.hideMTBEvents = prefs.hideMTBEvents;
+    // Preferences saved before event types existed default to races only
+    state.racesOnly = prefs.racesOnly !== false;
     
     // Update checkboxes
     if (elements.hideBMXCheckbox) elements.hideBMXCheckbox.checked = state.hideBMXEvents;
     if (elements.hideMTBCheckbox) elements.hideMTBCheckbox.checked = state.hideMTBEvents;
+    if (elements.racesOnlyCheckbox) elements.racesOnlyCheckbox.checked = state.racesOnly;
 }
 
 function saveState() {
@@ -325,7 +334,8 @@ function saveState() {
         state.statePreferences[state.selectedState] = {
             selectedClubs: Array.from(state.selectedClubs),
             hideBMXEvents: state.hideBMXEvents,
-            hideMTBEvents: state.hideMTBEvents
+            hideMTBEvents: state.hideMTBEvents,
+            racesOnly: state.racesOnly
         };
         
         const stateToSave = {
@@ -351,6 +361,11 @@ function getFilteredEvents() {
             return false;
         }
         
+        // Race filter; events without a type are races
+        if (state.racesOnly && event.type && event.type !== 'race') {
+            return false;
+        }
+        
         // BMX filter
         if (state.hideBMXEvents && event.eventName.toLowerCase().includes('bmx')) {
             return false;
@@ -574,7 +589,7 @@ function groupEventsByDate(events) {
 }
 
 function createDayPanel(dateKey, events) {
-    const date = new Date(dateKey);
+    const date = parseEventDate(dateKey);
     const section = document.createElement('div');
     section.className = 'list-day-section';
     
@@ -612,7 +627,7 @@ function createEventListItem(event) {
                 <svg fill="none" stroke="currentColor" viewBox="0 0 24 24">
                     <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M8 7V3m8 4V3m-9 8h10M5 21h14a2 2 0 002-2V7a2 2 0 00-2-2H5a2 2 0 00-2 2v12a2 2 0 002 2z"/>
                 </svg>
-                <span>${formatDateShort(new Date(event.eventDate))}</span>
+                <span>${formatDateShort(parseEventDate(event.eventDate))}${event.endDate ? ` – ${formatDateShort(parseEventDate(event.endDate))}` : ''}</span>
             </div>
             <div class="list-event-detail">
                 <svg fill="none" stroke="currentColor" viewBox="0 0 24 24">
@@ -830,6 +845,13 @@ function formatDateLong(date) {
     return date.toLocaleDateString('en-AU', options);
 }
 
+// eventDate holds the event's local calendar date ("2025-07-05T00:00:00Z"), so
+// read the date part as a local date rather than converting from UTC.
+function parseEventDate(dateString) {
+    const [year, month, day] = dateString.slice(0, 10).split('-').map(Number);
+    return new Date(year, month - 1, day);
+}
+
 function formatDateShort(date) {
     const options = { month: 'short', day: 'numeric' };
     return date.toLocaleDateString('en-AU', options);
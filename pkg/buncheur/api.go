@@ -0,0 +1,165 @@
+package buncheur
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/retry"
+)
+
+// maxPages bounds how many pages of the events API are read in one fetch, in
+// case the API keeps pointing at a next page.
+const maxPages = 50
+
+// apiEvent is an event record from the events API. URLs may be relative to
+// the site; start and end are dates or RFC 3339 timestamps.
+type apiEvent struct {
+	Title        string `json:"title"`
+	Club         string `json:"club"`
+	ClubURL      string `json:"club_url"`
+	URL          string `json:"url"`
+	Start        string `json:"start"`
+	End          string `json:"end"`
+	State        string `json:"state"`
+	ItemCategory string `json:"item_category"`
+	Venue        string `json:"venue"`
+}
+
+// apiPage is one page of the events API when it pages its results. A single
+// page is a bare array of events.
+type apiPage struct {
+	Events  []json.RawMessage `json:"events"`
+	Results []json.RawMessage `json:"results"`
+	Next    string            `json:"next"`
+}
+
+// fetchRecords reads every page of the events API from pageURL onwards. The
+// next page is given by the page's "next" field or a Link header. Records are
+// returned undecoded, so one malformed record does not spoil the rest.
+func (s *Source) fetchRecords(ctx context.Context, pageURL string) ([]json.RawMessage, error) {
+	var records []json.RawMessage
+	seen := make(map[string]bool)
+	for page := 1; pageURL != ""; page++ {
+		if page > maxPages {
+			calendar.Logf(s.Log, "Warning: stopped reading Buncheur events after %d pages\n", maxPages)
+			break
+		}
+		if seen[pageURL] {
+			break
+		}
+		seen[pageURL] = true
+
+		pageRecords, next, err := s.fetchPage(ctx, pageURL)
+		if err != nil {
+			if page > 1 {
+				return nil, fmt.Errorf("failed to fetch page %d of Buncheur events: %w", page, err)
+			}
+			return nil, err
+		}
+		records = append(records, pageRecords...)
+		pageURL = next
+	}
+	return records, nil
+}
+
+// fetchPage reads one page of the events API, returning its records and the
+// absolute URL of the next page, or "" if it is the last.
+func (s *Source) fetchPage(ctx context.Context, pageURL string) ([]json.RawMessage, string, error) {
+	var records []json.RawMessage
+	var next string
+	err := s.Retry.Do(ctx, func() error {
+		if err := s.Limiter.WaitURL(ctx, pageURL); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch Buncheur events: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if err := retry.CheckResponse(resp); err != nil {
+			return err
+		}
+
+		var body json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return fmt.Errorf("failed to decode Buncheur JSON: %w", err)
+		}
+		records, next, err = decodePage(body)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			next = linkNext(resp.Header.Values("Link"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if next != "" {
+		base, err := url.Parse(pageURL)
+		if err != nil {
+			return nil, "", err
+		}
+		ref, err := url.Parse(next)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid next page %q: %w", next, err)
+		}
+		next = base.ResolveReference(ref).String()
+	}
+	return records, next, nil
+}
+
+// decodePage splits a page of the events API into its records and the link
+// to the next page.
+func decodePage(body json.RawMessage) ([]json.RawMessage, string, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var records []json.RawMessage
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, "", fmt.Errorf("failed to decode Buncheur JSON: %w", err)
+		}
+		return records, "", nil
+	}
+
+	var page apiPage
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, "", fmt.Errorf("failed to decode Buncheur JSON: %w", err)
+	}
+	if page.Events == nil && page.Results == nil {
+		return nil, "", fmt.Errorf("failed to decode Buncheur JSON: no events in response")
+	}
+	return append(page.Events, page.Results...), page.Next, nil
+}
+
+// linkNext returns the target of the rel="next" link in Link headers, or "".
+func linkNext(headers []string) string {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				if strings.ReplaceAll(strings.TrimSpace(param), `"`, "") == "rel=next" {
+					return strings.Trim(target, "<>")
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,267 @@
+// Package buncheur fetches events from the Buncheur events API (www.buncheur.com).
+package buncheur
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/ratelimit"
+	"racecalendar/pkg/retry"
+)
+
+// Name is the source name recorded on Buncheur clubs and events.
+const Name = "Buncheur"
+
+// DefaultBaseURL is the public Buncheur site.
+const DefaultBaseURL = "https://www.buncheur.com"
+
+// Source reads the Buncheur events API. It implements calendar.Source.
+type Source struct {
+	// BaseURL is the site serving the /events API, without a trailing slash.
+	// Event and club URLs are built from it.
+	BaseURL string
+
+	// Client makes every request. It should have a timeout.
+	Client *http.Client
+
+	// Limiter throttles requests per host. Nil means no limit.
+	Limiter *ratelimit.PerHost
+
+	// Retry controls how failed API requests are retried.
+	Retry retry.Policy
+
+	Log calendar.Logger
+}
+
+// New returns a Buncheur source for the public site using client.
+func New(client *http.Client) *Source {
+	return &Source{BaseURL: DefaultBaseURL, Client: client, Retry: retry.DefaultPolicy()}
+}
+
+// Name implements calendar.Source.
+func (s *Source) Name() string {
+	return Name
+}
+
+// DiscoverClubs returns the organising clubs of every listed event. Buncheur
+// has no club directory, so clubs are only known through their events.
+func (s *Source) DiscoverClubs(ctx context.Context) ([]calendar.Club, error) {
+	result, err := s.fetch(ctx, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Invalid) > 0 {
+		calendar.Logf(s.Log, "Skipped %d invalid Buncheur records\n", len(result.Invalid))
+	}
+	return result.Clubs, nil
+}
+
+// FetchEvents returns the upcoming events Buncheur lists for the state, and
+// the clubs that run them. clubs, the state's known clubs, supply club pages
+// already found; see resolveClubs. Records that fail validation are reported
+// in the result's Invalid.
+func (s *Source) FetchEvents(ctx context.Context, state string, clubs []calendar.Club) (*calendar.Result, error) {
+	return s.fetch(ctx, state, clubs)
+}
+
+// fetch reads the events API, filtered to state unless it is empty.
+func (s *Source) fetch(ctx context.Context, state string, known []calendar.Club) (*calendar.Result, error) {
+	calendar.Logf(s.Log, "Fetching Buncheur events for state: %s\n", state)
+
+	url := s.BaseURL + "/events"
+	if state != "" {
+		url += "?state=" + state
+	}
+
+	records, err := s.fetchRecords(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar.Logf(s.Log, "Found %d events from Buncheur\n", len(records))
+
+	result := convertEvents(records, state, s.BaseURL, time.Now())
+	result.Clubs = s.resolveClubs(ctx, result.Clubs, known)
+	return result, nil
+}
+
+// convertEvents turns raw API records into upcoming events and the clubs that
+// run them. Records of other states are ignored and events that have finished
+// dropped; records that cannot be decoded or lack a title, state or valid
+// start are returned as Invalid. Event URLs in the payload are relative to
+// baseURL.
+func convertEvents(records []json.RawMessage, state, baseURL string, now time.Time) *calendar.Result {
+	result := &calendar.Result{}
+	seenClubs := make(map[string]bool)
+
+	for i, record := range records {
+		var be apiEvent
+		if err := json.Unmarshal(record, &be); err != nil {
+			result.Invalid = append(result.Invalid, calendar.Invalid{
+				Record: fmt.Sprintf("record %d", i+1),
+				Err:    fmt.Errorf("failed to decode: %w", err),
+			})
+			continue
+		}
+		// If we're filtering by state, skip others
+		if state != "" && be.State != "" && be.State != state {
+			continue
+		}
+		if err := be.validate(); err != nil {
+			result.Invalid = append(result.Invalid, calendar.Invalid{Record: be.describe(i), Err: err})
+			continue
+		}
+
+		eventDate, startTime := normaliseStart(be.Start, be.State)
+		if _, err := time.Parse(time.RFC3339, eventDate); err != nil {
+			result.Invalid = append(result.Invalid, calendar.Invalid{
+				Record: be.describe(i),
+				Err:    fmt.Errorf("invalid start %q", be.Start),
+			})
+			continue
+		}
+
+		fullUrl := baseURL + be.URL
+		event := calendar.Event{
+			EventName: be.Title,
+			EventDate: eventDate,
+			EndDate:   normaliseEnd(be.End, be.State, eventDate),
+			ClubName:  be.Club,
+			State:     be.State,
+			EventURL:  fullUrl,
+			Source:    Name,
+			Category:  be.ItemCategory,
+			TimeZone:  calendar.TimeZone(be.State),
+			StartTime: startTime,
+			Venue:     strings.TrimSpace(be.Venue),
+		}
+		event.ID = calendar.EventID(event) // "buncheur-<slug>"
+
+		// Keep multi-day events until their last day has passed, as for
+		// EntryBoss
+		if event.LastDate() < calendar.Cutoff(be.State, now) {
+			continue
+		}
+		result.Events = append(result.Events, event)
+
+		// Collect club info. Without a club link in the payload, the event
+		// page is followed to the club page later
+		if be.Club != "" && !seenClubs[be.Club+be.State] {
+			seenClubs[be.Club+be.State] = true
+			clubURL := be.ClubURL
+			if clubURL == "" {
+				clubURL = fullUrl
+			} else if strings.HasPrefix(clubURL, "/") {
+				clubURL = baseURL + clubURL
+			}
+			result.Clubs = append(result.Clubs, calendar.Club{
+				ClubName: be.Club,
+				ClubURL:  clubURL,
+				State:    be.State,
+				LastSeen: now.Format(time.RFC3339),
+				Source:   Name,
+				// Buncheur names its pages after the club, e.g. /armidale-cc-...
+				Identifiers: map[string]string{Name: calendar.Slug(be.Club)},
+			})
+		}
+	}
+
+	return result
+}
+
+// validate checks that the record has the fields every event needs.
+func (be apiEvent) validate() error {
+	switch {
+	case be.State == "":
+		return fmt.Errorf("no state")
+	case !knownState(be.State):
+		return fmt.Errorf("unknown state %q", be.State)
+	case be.Title == "":
+		return fmt.Errorf("no title")
+	case be.Start == "":
+		return fmt.Errorf("no start date")
+	case be.URL == "":
+		return fmt.Errorf("no url")
+	}
+	return nil
+}
+
+// describe names the record for reports: its title, or its URL or position.
+func (be apiEvent) describe(i int) string {
+	switch {
+	case be.Title != "":
+		return be.Title
+	case be.URL != "":
+		return be.URL
+	default:
+		return fmt.Sprintf("record %d", i+1)
+	}
+}
+
+func knownState(state string) bool {
+	for _, s := range calendar.States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// normaliseStart converts a Buncheur start value into an EventDate and a local
+// "15:04" start time. Dates ("2025-07-05") have no start time; timestamps with
+// an offset are converted to the state's zone first, so an event is never
+// shifted onto the wrong day, and timestamps without one are taken as local.
+func normaliseStart(start, state string) (eventDate, startTime string) {
+	if len(start) == 10 { // YYYY-MM-DD
+		return start + "T00:00:00Z", ""
+	}
+	if strings.HasSuffix(start, "T00:00:00Z") { // already a date in our layout
+		return start, ""
+	}
+
+	loc := calendar.Location(state)
+	t, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		// A timestamp without an offset is already local to the state
+		if t, err = time.ParseInLocation("2006-01-02T15:04:05", start, loc); err != nil {
+			return start, ""
+		}
+	}
+
+	local := t.In(loc)
+	eventDate = local.Format("2006-01-02") + "T00:00:00Z"
+	// A bare midnight usually means the API had no time for the event
+	if local.Hour() != 0 || local.Minute() != 0 {
+		startTime = local.Format("15:04")
+	}
+	return eventDate, startTime
+}
+
+// normaliseEnd converts a Buncheur end value into the EndDate of an event
+// starting on eventDate, or "" if the event ends the day it starts. The end of
+// an all-day event is exclusive, like the start of the following day, so a
+// date or a bare midnight ends the day before.
+func normaliseEnd(end, state, eventDate string) string {
+	if end == "" {
+		return ""
+	}
+
+	endDate, endTime := normaliseStart(end, state)
+	if endTime == "" {
+		t, err := time.Parse("2006-01-02T15:04:05Z", endDate)
+		if err != nil {
+			return ""
+		}
+		endDate = t.AddDate(0, 0, -1).Format("2006-01-02T15:04:05Z")
+	}
+
+	if endDate <= eventDate {
+		return ""
+	}
+	return endDate
+}
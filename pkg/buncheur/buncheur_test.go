@@ -0,0 +1,237 @@
+package buncheur
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/retry"
+)
+
+const eventsJSON = `[
+  {"title": "Summer Crit Race 1", "club": "Manning Valley CC", "url": "/mvcc-race-1", "start": "2099-01-07", "state": "NSW", "item_category": "Criterium"},
+  {"title": "Summer Crit Race 2", "club": "Manning Valley CC", "url": "/mvcc-race-2", "start": "2099-01-14", "state": "NSW", "item_category": "Criterium"},
+  {"title": "No Date", "club": "Manning Valley CC", "url": "/mvcc-no-date", "state": "NSW"},
+  {"title": "Wrong State", "club": "Brunswick CC", "url": "/bcc", "start": "2099-01-07", "state": "VIC"}
+]`
+
+func TestFetchEvents(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			fmt.Fprint(w, "<html><body>No club link</body></html>")
+			return
+		}
+		gotQuery = r.URL.RawQuery
+		fmt.Fprint(w, eventsJSON)
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+
+	result, err := src.FetchEvents(context.Background(), "NSW", nil)
+	if err != nil {
+		t.Fatalf("FetchEvents failed: %v", err)
+	}
+
+	if gotQuery != "state=NSW" {
+		t.Errorf("Query = %q, want %q", gotQuery, "state=NSW")
+	}
+	if len(result.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %d: %+v", len(result.Events), result.Events)
+	}
+	event := result.Events[0]
+	if event.EventDate != "2099-01-07T00:00:00Z" {
+		t.Errorf("EventDate = %q, want %q", event.EventDate, "2099-01-07T00:00:00Z")
+	}
+	if event.EventURL != server.URL+"/mvcc-race-1" {
+		t.Errorf("EventURL = %q, want %q", event.EventURL, server.URL+"/mvcc-race-1")
+	}
+	if event.Source != Name || event.Category != "Criterium" {
+		t.Errorf("Unexpected source or category: %+v", event)
+	}
+	if len(result.Clubs) != 1 || result.Clubs[0].ClubName != "Manning Valley CC" {
+		t.Errorf("Expected one club, got %+v", result.Clubs)
+	}
+}
+
+func TestFetchEventsResolvesClubPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			fmt.Fprint(w, eventsJSON)
+		case "/mvcc-race-1", "/mvcc-race-2", "/bcc-race-1":
+			fmt.Fprint(w, `<html><body>
+<a href="/mvcc-race-2">Summer Crit Race 2</a>
+<a href="/clubs/manning-valley-cc">Manning Valley CC</a>
+<a href="/clubs/brunswick-cc">Brunswick CC</a>
+</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+
+	// Brunswick CC was saved with the page of an event since finished
+	known := []calendar.Club{{
+		ClubName:    "Brunswick CC",
+		ClubURL:     server.URL + "/bcc-race-1",
+		State:       "NSW",
+		Source:      Name,
+		Identifiers: map[string]string{Name: "brunswick-cc"},
+	}}
+	result, err := src.FetchEvents(context.Background(), "NSW", known)
+	if err != nil {
+		t.Fatalf("FetchEvents failed: %v", err)
+	}
+
+	urls := make(map[string]string)
+	for _, c := range result.Clubs {
+		urls[c.ClubName] = c.ClubURL
+	}
+	want := map[string]string{
+		"Manning Valley CC": server.URL + "/clubs/manning-valley-cc",
+		"Brunswick CC":      server.URL + "/clubs/brunswick-cc",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("Club URLs = %v, want %v", urls, want)
+	}
+}
+
+func TestFetchEventsPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprint(w, `{"events": [{"title": "Race 1", "club": "Test Club", "url": "/race-1", "start": "2099-01-07", "state": "NSW"}], "next": "/events?state=NSW&page=2"}`)
+		case "2":
+			w.Header().Set("Link", `<`+server.URL+`/events?state=NSW&page=3>; rel="next"`)
+			fmt.Fprint(w, `{"results": [{"title": "Race 2", "club": "Test Club", "url": "/race-2", "start": "2099-01-14", "state": "NSW"}]}`)
+		case "3":
+			fmt.Fprint(w, `[{"title": "Race 3", "club": "Test Club", "url": "/race-3", "start": "2099-01-21", "state": "NSW"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+
+	records, err := src.fetchRecords(context.Background(), server.URL+"/events?state=NSW")
+	if err != nil {
+		t.Fatalf("fetchRecords failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("Expected 3 records across 3 pages, got %d", len(records))
+	}
+}
+
+func TestConvertEventsValidates(t *testing.T) {
+	records := []json.RawMessage{
+		json.RawMessage(`{"title": "Upcoming", "club": "Test Club", "url": "/upcoming", "start": "2025-07-12", "state": "VIC", "venue": " Casey Fields "}`),
+		json.RawMessage(`{"title": "Yesterday", "club": "Test Club", "url": "/yesterday", "start": "2025-07-09", "state": "VIC"}`),
+		json.RawMessage(`{"title": "Finished", "club": "Test Club", "url": "/finished", "start": "2025-07-01", "state": "VIC"}`),
+		json.RawMessage(`{"title": "Tour", "club": "Test Club", "url": "/tour", "start": "2025-07-05", "end": "2025-07-12", "state": "VIC"}`),
+		json.RawMessage(`{"title": 42, "club": "Test Club", "url": "/typed", "start": "2025-07-12", "state": "VIC"}`),
+		json.RawMessage(`{"title": "No Start", "club": "Test Club", "url": "/no-start", "state": "VIC"}`),
+		json.RawMessage(`{"title": "Bad Start", "club": "Test Club", "url": "/bad-start", "start": "next Saturday", "state": "VIC"}`),
+		json.RawMessage(`{"title": "No State", "club": "Test Club", "url": "/no-state", "start": "2025-07-12"}`),
+		json.RawMessage(`{"title": "Other State", "club": "Test Club", "url": "/other", "start": "2025-07-12", "state": "NSW"}`),
+	}
+	now := time.Date(2025, 7, 10, 6, 0, 0, 0, time.UTC)
+
+	result := convertEvents(records, "VIC", "https://www.buncheur.com", now)
+
+	var names []string
+	for _, e := range result.Events {
+		names = append(names, e.EventName)
+	}
+	if want := []string{"Upcoming", "Yesterday", "Tour"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Events = %v, want %v", names, want)
+	}
+	if result.Events[0].Venue != "Casey Fields" {
+		t.Errorf("Venue = %q, want %q", result.Events[0].Venue, "Casey Fields")
+	}
+
+	var invalid []string
+	for _, r := range result.Invalid {
+		invalid = append(invalid, r.Record)
+	}
+	if want := []string{"record 5", "No Start", "Bad Start", "No State"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("Invalid = %v, want %v", invalid, want)
+	}
+}
+
+func TestFetchEventsNon200(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Retry = retry.Policy{Retries: 1, BaseDelay: time.Millisecond}
+
+	if _, err := src.FetchEvents(context.Background(), "NSW", nil); err == nil {
+		t.Error("Expected an error for a 503 response")
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestNormaliseStart(t *testing.T) {
+	testCases := []struct {
+		start, state    string
+		eventDate, time string
+	}{
+		{"2025-07-05", "NSW", "2025-07-05T00:00:00Z", ""},
+		{"2025-07-05T00:00:00Z", "WA", "2025-07-05T00:00:00Z", ""},
+		{"2025-07-04T22:30:00Z", "NSW", "2025-07-05T00:00:00Z", "08:30"},
+		{"2025-07-05T08:30:00+10:00", "WA", "2025-07-05T00:00:00Z", "06:30"},
+		{"2025-07-05T18:45:00", "SA", "2025-07-05T00:00:00Z", "18:45"},
+		{"next Saturday", "VIC", "next Saturday", ""},
+	}
+
+	for _, tc := range testCases {
+		eventDate, startTime := normaliseStart(tc.start, tc.state)
+		if eventDate != tc.eventDate || startTime != tc.time {
+			t.Errorf("normaliseStart(%q, %q) = (%q, %q), want (%q, %q)", tc.start, tc.state, eventDate, startTime, tc.eventDate, tc.time)
+		}
+	}
+}
+
+func TestNormaliseEnd(t *testing.T) {
+	testCases := []struct {
+		end, state, eventDate string
+		want                  string
+	}{
+		{"", "VIC", "2025-07-05T00:00:00Z", ""},
+		// All-day ends are exclusive
+		{"2025-07-08", "VIC", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+		{"2025-07-06", "VIC", "2025-07-05T00:00:00Z", ""},
+		{"2025-07-07T04:00:00Z", "VIC", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+		{"2025-07-07T14:00:00Z", "VIC", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+		{"2025-07-05T17:00:00+10:00", "VIC", "2025-07-05T00:00:00Z", ""},
+		{"someday", "VIC", "2025-07-05T00:00:00Z", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := normaliseEnd(tc.end, tc.state, tc.eventDate); got != tc.want {
+			t.Errorf("normaliseEnd(%q, %q, %q) = %q, want %q", tc.end, tc.state, tc.eventDate, got, tc.want)
+		}
+	}
+}
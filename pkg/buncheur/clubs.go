@@ -0,0 +1,186 @@
+package buncheur
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/retry"
+)
+
+// clubPathSegments mark a link as a club or organiser page, e.g.
+// /clubs/manning-valley-cc.
+var clubPathSegments = map[string]bool{
+	"club": true, "clubs": true,
+	"organiser": true, "organisers": true,
+	"organizer": true, "organizers": true,
+}
+
+// resolveClubs gives each club its Buncheur club page. The events API has no
+// club links, so a club's URL starts as the page of one of its events, and
+// the club page is found by following it. Known clubs, from clubs.json, are
+// reused when they already have a club page, and otherwise resolved too, so
+// clubs saved with an event page are backfilled even without current events.
+// Clubs that cannot be resolved keep the URL they had.
+func (s *Source) resolveClubs(ctx context.Context, found, known []calendar.Club) []calendar.Club {
+	pages := make(map[string]string) // slug to club page
+	for _, c := range known {
+		if slug := c.Identifier(Name); slug != "" && s.isClubPage(c.ClubURL, slug) {
+			pages[slug] = c.ClubURL
+		}
+	}
+
+	clubs := append([]calendar.Club(nil), found...)
+	listed := make(map[string]bool)
+	for _, c := range clubs {
+		listed[c.Identifier(Name)] = true
+	}
+	for _, c := range known {
+		if slug := c.Identifier(Name); slug != "" && !listed[slug] && pages[slug] == "" && c.ClubURL != "" {
+			clubs = append(clubs, c)
+		}
+	}
+
+	resolved := 0
+	for i := range clubs {
+		club := &clubs[i]
+		slug := club.Identifier(Name)
+		if page := pages[slug]; page != "" {
+			club.ClubURL = page
+			continue
+		}
+		if s.isClubPage(club.ClubURL, slug) || club.ClubURL == "" {
+			continue
+		}
+
+		page, err := s.clubPage(ctx, club.ClubURL, club.ClubName)
+		if err != nil {
+			calendar.Logf(s.Log, "Could not find the club page of %s: %v\n", club.ClubName, err)
+			continue
+		}
+		club.ClubURL, pages[slug] = page, page
+		resolved++
+	}
+	if resolved > 0 {
+		calendar.Logf(s.Log, "Found club pages for %d Buncheur clubs\n", resolved)
+	}
+	return clubs
+}
+
+// isClubPage reports whether pageURL is a club's own page rather than one of
+// its events: a page off Buncheur, such as the club's website, or a Buncheur
+// page named after the club or under a clubs or organisers path.
+func (s *Source) isClubPage(pageURL, slug string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if base, err := url.Parse(s.BaseURL); err == nil && !strings.EqualFold(strings.TrimPrefix(u.Host, "www."), strings.TrimPrefix(base.Host, "www.")) {
+		return true
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if clubPathSegments[strings.ToLower(segment)] {
+			return true
+		}
+	}
+	return slug != "" && segments[len(segments)-1] == slug
+}
+
+// clubPage follows an event page to its club's page. Links score for being
+// named after the club (its slug), titled with its name and under a clubs or
+// organisers path; the best link on Buncheur wins, and a link elsewhere, such
+// as the club's website, only if it is titled with the club's name.
+func (s *Source) clubPage(ctx context.Context, eventURL, clubName string) (string, error) {
+	doc, err := s.fetchDocument(ctx, eventURL)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(eventURL)
+	if err != nil {
+		return "", err
+	}
+
+	slug := calendar.Slug(clubName)
+	best, bestScore := "", 0
+	doc.Find("a[href]").Each(func(i int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		ref, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		abs := base.ResolveReference(ref)
+		abs.Fragment = ""
+		if abs.String() == eventURL || (abs.Scheme != "http" && abs.Scheme != "https") {
+			return
+		}
+
+		named := strings.EqualFold(strings.TrimSpace(link.Text()), clubName)
+		score := 0
+		if abs.Host != base.Host {
+			if named {
+				score = 1
+			}
+		} else {
+			segments := strings.Split(strings.Trim(abs.Path, "/"), "/")
+			if segments[len(segments)-1] == slug {
+				score += 4
+			}
+			if named {
+				score += 2
+			}
+			for _, segment := range segments[:len(segments)-1] {
+				if clubPathSegments[strings.ToLower(segment)] {
+					score++
+					break
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = abs.String(), score
+		}
+	})
+
+	if best == "" {
+		return "", fmt.Errorf("no club link on %s", eventURL)
+	}
+	return best, nil
+}
+
+// fetchDocument fetches and parses an HTML page, retrying transient failures.
+func (s *Source) fetchDocument(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	var doc *goquery.Document
+	err := s.Retry.Do(ctx, func() error {
+		if err := s.Limiter.WaitURL(ctx, pageURL); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if err := retry.CheckResponse(resp); err != nil {
+			return err
+		}
+
+		doc, err = goquery.NewDocumentFromReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		return nil
+	})
+	return doc, err
+}
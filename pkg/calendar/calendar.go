@@ -0,0 +1,201 @@
+// Package calendar holds the data model shared by every event source and the
+// merge/persist layer that turns scraped events into the static JSON files
+// served by the website.
+package calendar
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// States lists the Australian state and territory codes, in the order they are
+// processed and reported.
+var States = []string{"ACT", "NSW", "NT", "QLD", "SA", "TAS", "VIC", "WA"}
+
+// Club represents a cycling club
+type Club struct {
+	// ID identifies the club across sources and runs; see Registry.
+	ID string `json:"id,omitempty"`
+
+	ClubName string `json:"clubName"`
+	ClubURL  string `json:"clubUrl"`
+	State    string `json:"state"`
+	LastSeen string `json:"lastSeen"`
+	Source   string `json:"source"`
+
+	// Identifiers are the club's identity on each source that lists it,
+	// keyed by source name; see Identifier. Aliases are the other names
+	// the club goes by.
+	Identifiers map[string]string `json:"identifiers,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+}
+
+// Event represents a cycling event. EventDate is the event's calendar date
+// in its local time zone, written as midnight UTC ("2006-01-02T00:00:00Z") for
+// compatibility with existing clients; use Start for the actual moment.
+type Event struct {
+	// ID identifies the event across runs; see EventID.
+	ID string `json:"id,omitempty"`
+
+	EventName string `json:"eventName"`
+	EventDate string `json:"eventDate"`
+	ClubName  string `json:"clubName"`
+	State     string `json:"state"`
+	EventURL  string `json:"eventUrl"`
+	Source    string `json:"source"`
+	Category  string `json:"category"`
+
+	// Discipline is the kind of riding, e.g. "criterium", "track" or "bmx",
+	// assigned by Rules; DisciplineReason says which rule assigned it.
+	Discipline       string `json:"discipline,omitempty"`
+	DisciplineReason string `json:"disciplineReason,omitempty"`
+
+	// Type says what the event is: a "race", or a "training", "coaching" or
+	// "social" session, or a listing selling "membership" or "merchandise".
+	// It is assigned by Rules, like Discipline.
+	Type       string `json:"type,omitempty"`
+	TypeReason string `json:"typeReason,omitempty"`
+
+	// EndDate is the last day of an event that spans several days, such as
+	// a tour or carnival, in the same layout as EventDate. It is empty for
+	// single-day events.
+	EndDate string `json:"endDate,omitempty"`
+
+	// DateConfidence, from 0 to 1, is how sure a scraper is that EventDate
+	// belongs to this event, and DateMethod where it was found, e.g. "link",
+	// "ancestor" or "race page". Dates below 0.5 are worth reviewing. Both are
+	// empty where the source gives dates directly.
+	DateConfidence float64 `json:"dateConfidence,omitempty"`
+	DateMethod     string  `json:"dateMethod,omitempty"`
+
+	// TimeZone is the IANA zone the event's date and times are local to,
+	// derived from its state.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Details from the event's own page, where the source provides one.
+	// StartTime is the local start time as "15:04"; EntriesClose uses the
+	// same layout as EventDate.
+	StartTime    string   `json:"startTime,omitempty"`
+	Venue        string   `json:"venue,omitempty"`
+	EntriesClose string   `json:"entriesClose,omitempty"`
+	Grades       []string `json:"grades,omitempty"`
+	Status       string   `json:"status,omitempty"`
+
+	// FirstSeen and LastSeen are when a run first and last found the event,
+	// and LastChanged when its details last differed from the previous run,
+	// all as RFC 3339 timestamps. They are maintained by MergeEvents.
+	FirstSeen   string `json:"firstSeen,omitempty"`
+	LastSeen    string `json:"lastSeen,omitempty"`
+	LastChanged string `json:"lastChanged,omitempty"`
+
+	// Stale is set on an event carried forward from a previous run because
+	// its club could not be fetched; StaleSince is when that first happened.
+	Stale      bool   `json:"stale,omitempty"`
+	StaleSince string `json:"staleSince,omitempty"`
+
+	// Listings are the same event as listed by other sources, folded into
+	// this record by Dedupe so that it appears once.
+	Listings []Event `json:"listings,omitempty"`
+}
+
+// LastDate returns the last day of the event as "2006-01-02": its EndDate if
+// it has one, otherwise its EventDate. It returns "" if neither is a date.
+func (e Event) LastDate() string {
+	last := ""
+	if len(e.EventDate) >= 10 {
+		last = e.EventDate[:10]
+	}
+	if len(e.EndDate) >= 10 && e.EndDate[:10] > last {
+		last = e.EndDate[:10]
+	}
+	return last
+}
+
+// EventID returns the event's ID, deriving one for events that have none:
+// the site and last path segment of its page, such as "entryboss-28757" for
+// https://entryboss.cc/races/28757 or "buncheur-some-club-crit" for a
+// Buncheur event, or else a hash of its source, club, name and date.
+func EventID(e Event) string {
+	if e.ID != "" {
+		return e.ID
+	}
+
+	if u, err := url.Parse(e.EventURL); err == nil && u.Host != "" {
+		host := strings.Split(strings.TrimPrefix(u.Hostname(), "www."), ".")[0]
+		path := strings.Trim(u.Path, "/")
+		if last := path[strings.LastIndex(path, "/")+1:]; host != "" && last != "" {
+			return host + "-" + last
+		}
+	}
+
+	sum := sha1.Sum([]byte(strings.Join([]string{e.Source, e.State, e.ClubName, e.EventName, e.EventDate}, "|")))
+	return "event-" + hex.EncodeToString(sum[:8])
+}
+
+// Source is a provider of clubs and events, such as EntryBoss or Buncheur.
+type Source interface {
+	// Name identifies the source. It is stored in the Source field of every
+	// club and event the source produces.
+	Name() string
+
+	// DiscoverClubs returns every club the source currently lists.
+	DiscoverClubs(ctx context.Context) ([]Club, error)
+
+	// FetchEvents returns the upcoming events for a single state. clubs holds
+	// the known clubs in that state; sources that list events directly may
+	// ignore it.
+	FetchEvents(ctx context.Context, state string, clubs []Club) (*Result, error)
+}
+
+// Result is the outcome of fetching one state's events from a Source.
+type Result struct {
+	// Events are the upcoming events, with State and Source populated.
+	Events []Event
+
+	// Clubs are clubs seen while fetching events. They are added to clubs.json
+	// if no club with the same name already exists in the state.
+	Clubs []Club
+
+	// Failures lists the clubs whose events could not be fetched.
+	Failures []Failure
+
+	// Invalid lists the records the source returned that could not be
+	// turned into events, such as an event without a date.
+	Invalid []Invalid
+}
+
+// Invalid records a source record that failed validation.
+type Invalid struct {
+	// Record identifies the record, e.g. by its title or URL.
+	Record string
+	Err    error
+}
+
+// Failure records a club whose events could not be fetched.
+type Failure struct {
+	Club Club
+	Err  error
+
+	// Permanent is set when retrying cannot help, e.g. the club page returned 404.
+	Permanent bool
+}
+
+// ErrNoClubs is returned by FetchEvents when a source scrapes per club and
+// no clubs are known for the requested state. The state is skipped.
+var ErrNoClubs = errors.New("no clubs found for state")
+
+// Logger receives progress output. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// Logf writes to l, or does nothing if l is nil.
+func Logf(l Logger, format string, v ...any) {
+	if l != nil {
+		l.Printf(format, v...)
+	}
+}
@@ -0,0 +1,1140 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStructure(t *testing.T) {
+	// Test that Event struct can be marshaled to JSON correctly with state field
+	event := Event{
+		EventName: "Test Race",
+		EventDate: "2025-07-05T00:00:00Z",
+		ClubName:  "Test Club",
+		State:     "VIC",
+		EventURL:  "https://entryboss.cc/races/12345",
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to marshal event: %v", err)
+	}
+
+	var unmarshaled Event
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+
+	if !reflect.DeepEqual(unmarshaled, event) {
+		t.Errorf("Event marshal/unmarshal failed: got %+v, want %+v", unmarshaled, event)
+	}
+}
+
+func TestClubsJSONStructure(t *testing.T) {
+	// Test clubs.json structure with State and LastSeen fields
+	currentTime := time.Now().Format(time.RFC3339)
+	clubs := []Club{
+		{
+			ClubName: "Brunswick Cycling Club",
+			ClubURL:  "https://entryboss.cc/calendar/brunswick",
+			State:    "VIC",
+			LastSeen: currentTime,
+		},
+		{
+			ClubName: "Sydney Cycling Club",
+			ClubURL:  "https://entryboss.cc/calendar/sydney",
+			State:    "NSW",
+			LastSeen: currentTime,
+		},
+	}
+
+	data, err := json.MarshalIndent(clubs, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal clubs: %v", err)
+	}
+
+	var unmarshaled []Club
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal clubs: %v", err)
+	}
+
+	if len(unmarshaled) != 2 {
+		t.Errorf("Expected 2 clubs, got %d", len(unmarshaled))
+	}
+
+	// Verify State and LastSeen fields are preserved
+	for i, club := range unmarshaled {
+		if club.State == "" {
+			t.Errorf("Club %d missing State field", i)
+		}
+		if club.LastSeen == "" {
+			t.Errorf("Club %d missing LastSeen field", i)
+		}
+		if club.LastSeen != currentTime {
+			t.Errorf("Club %d LastSeen mismatch: got %q, want %q", i, club.LastSeen, currentTime)
+		}
+	}
+}
+
+func TestEventsJSONStructure(t *testing.T) {
+	// Test events.json structure with state field
+	now := time.Now()
+	events := []Event{
+		{
+			EventName: "Winter Criterium",
+			EventDate: now.Add(24 * time.Hour).Format("2006-01-02T15:04:05Z"),
+			ClubName:  "Brunswick Cycling Club",
+			State:     "VIC",
+			EventURL:  "https://entryboss.cc/races/12345",
+		},
+		{
+			EventName: "Road Race Championship",
+			EventDate: now.Add(48 * time.Hour).Format("2006-01-02T15:04:05Z"),
+			ClubName:  "Sydney Cycling Club",
+			State:     "NSW",
+			EventURL:  "https://entryboss.cc/races/12346",
+		},
+	}
+
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal events: %v", err)
+	}
+
+	// Write test events file for frontend testing
+	if err := os.WriteFile(filepath.Join(t.TempDir(), "events-vic.json"), data, 0644); err != nil {
+		t.Fatalf("Could not write test events-vic.json: %v", err)
+	}
+
+	var unmarshaled []Event
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal events: %v", err)
+	}
+
+	if len(unmarshaled) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(unmarshaled))
+	}
+
+	// Verify state field is preserved
+	for i, event := range unmarshaled {
+		if event.State == "" {
+			t.Errorf("Event %d missing State field", i)
+		}
+	}
+}
+
+func TestClubStateAndLastSeenFields(t *testing.T) {
+	// Test that Club struct properly handles State and LastSeen fields
+	currentTime := time.Now().Format(time.RFC3339)
+
+	club := Club{
+		ClubName: "Test Club",
+		ClubURL:  "https://entryboss.cc/calendar/test",
+		State:    "VIC",
+		LastSeen: currentTime,
+	}
+
+	// Test JSON marshaling
+	data, err := json.Marshal(club)
+	if err != nil {
+		t.Fatalf("Failed to marshal club with State and LastSeen: %v", err)
+	}
+
+	// Test JSON unmarshaling
+	var unmarshaled Club
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal club with State and LastSeen: %v", err)
+	}
+
+	if unmarshaled.State != "VIC" {
+		t.Errorf("State field not preserved: got %q, want %q", unmarshaled.State, "VIC")
+	}
+
+	if unmarshaled.LastSeen != currentTime {
+		t.Errorf("LastSeen field not preserved: got %q, want %q", unmarshaled.LastSeen, currentTime)
+	}
+
+	// Test that empty fields are handled
+	clubWithoutFields := Club{
+		ClubName: "Test Club 2",
+		ClubURL:  "https://entryboss.cc/calendar/test2",
+	}
+
+	data2, err := json.Marshal(clubWithoutFields)
+	if err != nil {
+		t.Fatalf("Failed to marshal club without State/LastSeen: %v", err)
+	}
+
+	var unmarshaled2 Club
+	if err := json.Unmarshal(data2, &unmarshaled2); err != nil {
+		t.Fatalf("Failed to unmarshal club without State/LastSeen: %v", err)
+	}
+
+	if unmarshaled2.State != "" {
+		t.Errorf("Empty State should remain empty, got %q", unmarshaled2.State)
+	}
+
+	if unmarshaled2.LastSeen != "" {
+		t.Errorf("Empty LastSeen should remain empty, got %q", unmarshaled2.LastSeen)
+	}
+}
+
+func TestMultiStateSupport(t *testing.T) {
+	// Test that we can handle clubs from multiple states
+	states := []string{"VIC", "NSW", "QLD", "SA", "WA", "TAS", "ACT", "NT"}
+	currentTime := time.Now().Format(time.RFC3339)
+
+	var clubs []Club
+	for i, state := range states {
+		clubs = append(clubs, Club{
+			ClubName: "Test Club " + state,
+			ClubURL:  "https://entryboss.cc/calendar/test-" + state,
+			State:    state,
+			LastSeen: currentTime,
+		})
+
+		// Verify each club has correct state
+		if clubs[i].State != state {
+			t.Errorf("Club %d state mismatch: got %q, want %q", i, clubs[i].State, state)
+		}
+	}
+
+	// Test marshaling all clubs
+	data, err := json.MarshalIndent(clubs, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal multi-state clubs: %v", err)
+	}
+
+	var unmarshaled []Club
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal multi-state clubs: %v", err)
+	}
+
+	if len(unmarshaled) != len(states) {
+		t.Errorf("Expected %d clubs, got %d", len(states), len(unmarshaled))
+	}
+}
+
+func TestMergeEventsReplacesOnlySameSource(t *testing.T) {
+	existing := []Event{
+		{EventName: "Old EntryBoss Race", EventDate: "2025-07-01T00:00:00Z", EventURL: "https://entryboss.cc/races/1", Source: "EntryBoss"},
+		{EventName: "Buncheur Crit", EventDate: "2025-07-03T00:00:00Z", EventURL: "https://www.buncheur.com/crit", Source: "Buncheur"},
+	}
+	fresh := []Event{
+		{EventName: "New EntryBoss Race", EventDate: "2025-07-02T00:00:00Z", EventURL: "https://entryboss.cc/races/2", Source: "EntryBoss"},
+	}
+
+	merged := MergeEvents(existing, "EntryBoss", fresh, time.Now())
+
+	if len(merged) != 2 {
+		t.Fatalf("Expected 2 events, got %d: %+v", len(merged), merged)
+	}
+	if merged[0].EventName != "New EntryBoss Race" || merged[1].EventName != "Buncheur Crit" {
+		t.Errorf("Unexpected merge result or order: %+v", merged)
+	}
+}
+
+func TestEventID(t *testing.T) {
+	testCases := []struct {
+		event Event
+		want  string
+	}{
+		{Event{EventURL: "https://entryboss.cc/races/28757"}, "entryboss-28757"},
+		{Event{EventURL: "https://www.buncheur.com/manly-crit/"}, "buncheur-manly-crit"},
+		{Event{ID: "entryboss-1", EventURL: "https://entryboss.cc/races/2"}, "entryboss-1"},
+	}
+	for _, tc := range testCases {
+		if got := EventID(tc.event); got != tc.want {
+			t.Errorf("EventID(%q) = %q, want %q", tc.event.EventURL, got, tc.want)
+		}
+	}
+
+	// Without a page, the ID is a hash that is the same on every run
+	e := Event{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", ClubName: "Test Club", State: "VIC"}
+	if id := EventID(e); len(id) != len("event-")+16 || id != EventID(e) {
+		t.Errorf("Unexpected hashed ID %q", id)
+	}
+}
+
+func TestMergeEventsTracksSeenAndChanged(t *testing.T) {
+	day1 := time.Date(2025, 7, 1, 6, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	crit := Event{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1", Source: "EntryBoss"}
+	road := Event{EventName: "Road Race", EventDate: "2025-07-06T00:00:00Z", EventURL: "https://entryboss.cc/races/2", Source: "EntryBoss"}
+
+	first := MergeEvents(nil, "EntryBoss", []Event{crit, road}, day1)
+	for _, e := range first {
+		if e.ID == "" || e.FirstSeen != day1.Format(time.RFC3339) || e.LastSeen != e.FirstSeen || e.LastChanged != e.FirstSeen {
+			t.Errorf("New event not stamped with day 1: %+v", e)
+		}
+	}
+
+	// The crit is unchanged, the road race moved and a stale copy is carried
+	moved := road
+	moved.EventDate = "2025-07-13T00:00:00Z"
+	moved.Stale = true
+	second := MergeEvents(first, "EntryBoss", []Event{crit, moved}, day2)
+
+	want := map[string][3]string{
+		"entryboss-1": {"2025-07-01T06:00:00Z", "2025-07-02T06:00:00Z", "2025-07-01T06:00:00Z"},
+		"entryboss-2": {"2025-07-01T06:00:00Z", "2025-07-01T06:00:00Z", "2025-07-02T06:00:00Z"},
+	}
+	for _, e := range second {
+		if got := [3]string{e.FirstSeen, e.LastSeen, e.LastChanged}; got != want[e.ID] {
+			t.Errorf("%s: firstSeen, lastSeen, lastChanged = %v, want %v", e.ID, got, want[e.ID])
+		}
+	}
+}
+
+func TestMergeEventsReplacesLegacyEvents(t *testing.T) {
+	// Events saved before sources were recorded have no source
+	existing := []Event{
+		{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1"},
+		{EventName: "Gone Race", EventDate: "2025-07-06T00:00:00Z", EventURL: "https://entryboss.cc/races/2"},
+	}
+	fresh := []Event{
+		{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1", Source: "EntryBoss"},
+	}
+
+	merged := MergeEvents(existing, "EntryBoss", fresh, time.Now())
+	if len(merged) != 2 || merged[0].Source != "EntryBoss" || merged[1].EventName != "Gone Race" {
+		t.Errorf("Expected the legacy crit to be replaced and the other kept, got %+v", merged)
+	}
+}
+
+func TestMergeClubs(t *testing.T) {
+	existing := []Club{
+		{ClubName: "Old Name", ClubURL: "https://entryboss.cc/calendar/a", State: "VIC", LastSeen: "2025-01-01T00:00:00Z", Source: "EntryBoss"},
+		{ClubName: "Legacy Club", ClubURL: "https://entryboss.cc/calendar/b", State: "NSW"},
+	}
+	now := "2025-07-01T00:00:00Z"
+	scraped := []Club{
+		{ClubName: "New Name", ClubURL: "https://entryboss.cc/calendar/a", State: "VIC", LastSeen: now, Source: "EntryBoss"},
+		// Discovered a moment before the merge; it is last seen with the rest
+		{ClubName: "Fresh Club", ClubURL: "https://entryboss.cc/calendar/c", State: "QLD", LastSeen: "2025-06-30T23:59:58Z", Source: "EntryBoss"},
+	}
+
+	merge := MergeClubs(existing, scraped, "EntryBoss", now)
+
+	if len(merge.Clubs) != 3 {
+		t.Fatalf("Expected 3 clubs, got %d", len(merge.Clubs))
+	}
+	if merge.Updated != 1 || len(merge.Added) != 1 || merge.Migrated != 1 {
+		t.Errorf("Unexpected stats: updated=%d added=%v migrated=%d", merge.Updated, merge.Added, merge.Migrated)
+	}
+	for _, club := range merge.Clubs {
+		switch club.ClubURL {
+		case "https://entryboss.cc/calendar/a":
+			if club.ClubName != "New Name" || club.LastSeen != now {
+				t.Errorf("Existing club not refreshed: %+v", club)
+			}
+		case "https://entryboss.cc/calendar/b":
+			if club.Source != "EntryBoss" || club.LastSeen != now {
+				t.Errorf("Legacy club not migrated: %+v", club)
+			}
+		case "https://entryboss.cc/calendar/c":
+			if club.LastSeen != now {
+				t.Errorf("New club lastSeen = %q, want the run's %q", club.LastSeen, now)
+			}
+		}
+	}
+}
+
+func TestAddClubsKeepsExisting(t *testing.T) {
+	existing := []Club{
+		{ClubName: "Brunswick Cycling Club", ClubURL: "https://entryboss.cc/calendar/brunswick", State: "VIC", Source: "EntryBoss"},
+	}
+	found := []Club{
+		{ClubName: "Brunswick Cycling Club", ClubURL: "https://www.buncheur.com/x", State: "VIC", Source: "Buncheur"},
+		{ClubName: "Brunswick Cycling Club", ClubURL: "https://www.buncheur.com/y", State: "NSW", Source: "Buncheur"},
+	}
+
+	merged, added := AddClubs(existing, found)
+
+	if added != 1 || len(merged) != 2 {
+		t.Fatalf("Expected 1 club added to make 2, got added=%d total=%d", added, len(merged))
+	}
+	if merged[1].ClubURL != existing[0].ClubURL {
+		t.Errorf("Existing club was modified: %+v", merged[1])
+	}
+}
+
+func TestAddClubsBackfillsSourceURL(t *testing.T) {
+	existing := []Club{
+		{ClubName: "Manning Valley CC", ClubURL: "https://www.buncheur.com/mvcc-race-1", State: "NSW", Source: "Buncheur"},
+	}
+	found := []Club{
+		{ClubName: "Manning Valley CC", ClubURL: "https://www.buncheur.com/clubs/manning-valley-cc", State: "NSW", Source: "Buncheur"},
+	}
+
+	merged, added := AddClubs(existing, found)
+
+	if added != 0 || len(merged) != 1 {
+		t.Fatalf("Expected no clubs added, got added=%d total=%d", added, len(merged))
+	}
+	if merged[0].ClubURL != found[0].ClubURL {
+		t.Errorf("ClubURL = %q, want %q", merged[0].ClubURL, found[0].ClubURL)
+	}
+}
+
+func TestCarryForward(t *testing.T) {
+	now := time.Date(2025, 7, 10, 6, 0, 0, 0, time.UTC)
+	existing := []Event{
+		{EventName: "Past Race", EventDate: "2025-07-01T00:00:00Z", ClubName: "Failed Club", State: "VIC", Source: "EntryBoss"},
+		{EventName: "Future Race", EventDate: "2025-07-12T00:00:00Z", ClubName: "Failed Club", State: "VIC", Source: "EntryBoss"},
+		{EventName: "Already Stale", EventDate: "2025-07-20T00:00:00Z", ClubName: "Failed Club", State: "VIC", Source: "EntryBoss", Stale: true, StaleSince: "2025-07-09T06:00:00Z"},
+		{EventName: "Healthy Race", EventDate: "2025-07-12T00:00:00Z", ClubName: "Healthy Club", State: "VIC", Source: "EntryBoss"},
+		{EventName: "Other Source", EventDate: "2025-07-12T00:00:00Z", ClubName: "Failed Club", State: "VIC", Source: "Buncheur"},
+	}
+	failures := []Failure{{Club: Club{ClubName: "Failed Club", State: "VIC"}}}
+
+	kept := CarryForward(existing, "EntryBoss", failures, now)
+
+	if len(kept) != 2 {
+		t.Fatalf("Expected 2 carried events, got %d: %+v", len(kept), kept)
+	}
+	if kept[0].EventName != "Future Race" || !kept[0].Stale || kept[0].StaleSince != now.Format(time.RFC3339) {
+		t.Errorf("Future event not marked stale: %+v", kept[0])
+	}
+	if kept[1].StaleSince != "2025-07-09T06:00:00Z" {
+		t.Errorf("Already stale event lost its StaleSince: %+v", kept[1])
+	}
+}
+
+func TestCarryForwardKeepsEventsInProgress(t *testing.T) {
+	now := time.Date(2025, 7, 10, 6, 0, 0, 0, time.UTC)
+	existing := []Event{
+		{EventName: "Tour", EventDate: "2025-07-07T00:00:00Z", EndDate: "2025-07-11T00:00:00Z", ClubName: "Failed Club", State: "VIC", Source: "EntryBoss"},
+		{EventName: "Finished Tour", EventDate: "2025-07-01T00:00:00Z", EndDate: "2025-07-03T00:00:00Z", ClubName: "Failed Club", State: "VIC", Source: "EntryBoss"},
+	}
+	failures := []Failure{{Club: Club{ClubName: "Failed Club", State: "VIC"}}}
+
+	kept := CarryForward(existing, "EntryBoss", failures, now)
+
+	if len(kept) != 1 || kept[0].EventName != "Tour" {
+		t.Errorf("Expected only the tour in progress to be carried, got %+v", kept)
+	}
+}
+
+func TestLastDate(t *testing.T) {
+	testCases := []struct {
+		event Event
+		want  string
+	}{
+		{Event{EventDate: "2025-07-05T00:00:00Z"}, "2025-07-05"},
+		{Event{EventDate: "2025-07-05T00:00:00Z", EndDate: "2025-07-07T00:00:00Z"}, "2025-07-07"},
+		{Event{EventDate: "2025-07-05T00:00:00Z", EndDate: "2025-07-01T00:00:00Z"}, "2025-07-05"},
+		{Event{EventDate: "TBC"}, ""},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.event.LastDate(); got != tc.want {
+			t.Errorf("%+v.LastDate() = %q, want %q", tc.event, got, tc.want)
+		}
+	}
+}
+
+// fakeSource returns canned results for each state.
+type fakeSource struct {
+	results map[string]*Result
+}
+
+func (f *fakeSource) Name() string { return "EntryBoss" }
+
+func (f *fakeSource) DiscoverClubs(ctx context.Context) ([]Club, error) { return nil, nil }
+
+func (f *fakeSource) FetchEvents(ctx context.Context, state string, clubs []Club) (*Result, error) {
+	if len(clubs) == 0 {
+		return nil, ErrNoClubs
+	}
+	return f.results[state], nil
+}
+
+func TestUpdaterKeepsEventsOfFailedClubs(t *testing.T) {
+	store := NewStore(t.TempDir())
+	clubs := []Club{
+		{ClubName: "Failed Club", ClubURL: "https://entryboss.cc/calendar/failed", State: "VIC", Source: "EntryBoss"},
+		{ClubName: "Healthy Club", ClubURL: "https://entryboss.cc/calendar/healthy", State: "VIC", Source: "EntryBoss"},
+	}
+	if err := store.SaveClubs(clubs); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().AddDate(0, 1, 0).Format("2006-01-02") + "T00:00:00Z"
+	if err := store.SaveEvents("VIC", []Event{
+		{EventName: "Failed Club Race", EventDate: future, ClubName: "Failed Club", State: "VIC", EventURL: "https://entryboss.cc/races/1", Source: "EntryBoss"},
+		{EventName: "Dropped Race", EventDate: future, ClubName: "Healthy Club", State: "VIC", EventURL: "https://entryboss.cc/races/2", Source: "EntryBoss"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &fakeSource{results: map[string]*Result{
+		"VIC": {
+			Events:   []Event{{EventName: "Healthy Club Race", EventDate: future, ClubName: "Healthy Club", State: "VIC", EventURL: "https://entryboss.cc/races/3", Source: "EntryBoss"}},
+			Failures: []Failure{{Club: clubs[0], Err: errors.New("timeout")}},
+		},
+	}}
+
+	u := &Updater{Store: store}
+	if err := u.UpdateEvents(context.Background(), src, []string{"VIC", "NT"}); err != nil {
+		t.Fatalf("UpdateEvents failed: %v", err)
+	}
+
+	events, err := store.LoadEvents("VIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]Event)
+	for _, e := range events {
+		names[e.EventName] = e
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", events)
+	}
+	if e, ok := names["Failed Club Race"]; !ok || !e.Stale {
+		t.Errorf("Failed club's event should be kept and marked stale: %+v", events)
+	}
+	if e, ok := names["Healthy Club Race"]; !ok || e.Stale {
+		t.Errorf("Fresh event missing or stale: %+v", events)
+	}
+}
+
+func TestGuardCheckEvents(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	makeEvents := func(club string, n int) []Event {
+		var events []Event
+		for i := 0; i < n; i++ {
+			events = append(events, Event{EventName: "Race", EventDate: "2025-07-05T00:00:00Z", ClubName: club, Source: "EntryBoss"})
+		}
+		return events
+	}
+
+	before := append(makeEvents("Busy Club", 6), makeEvents("Quiet Club", 14)...)
+	g := DefaultGuard()
+
+	// A small drop from the same clubs is fine
+	if err := g.CheckEvents("VIC", "EntryBoss", before, append(makeEvents("Busy Club", 5), makeEvents("Quiet Club", 12)...), now); err != nil {
+		t.Errorf("Expected small drop to pass, got %v", err)
+	}
+
+	// Losing everything trips the percentage check
+	err := g.CheckEvents("VIC", "EntryBoss", before, makeEvents("Quiet Club", 2), now)
+	var shrinkage *ShrinkageError
+	if !errors.As(err, &shrinkage) {
+		t.Fatalf("Expected ShrinkageError, got %v", err)
+	}
+	if shrinkage.File != "events-vic.json" || len(shrinkage.Problems) != 2 {
+		t.Errorf("Unexpected report: %+v", shrinkage)
+	}
+
+	// A busy club emptying trips the guard even if the total is fine
+	if err := g.CheckEvents("VIC", "EntryBoss", before, makeEvents("Quiet Club", 14), now); err == nil {
+		t.Error("Expected busy club dropping to zero to trip the guard")
+	}
+
+	// Other sources and past events are not counted
+	past := []Event{{EventName: "Old", EventDate: "2025-06-01T00:00:00Z", ClubName: "Busy Club", Source: "EntryBoss"}}
+	other := makeEvents("Busy Club", 20)
+	for i := range other {
+		other[i].Source = "Buncheur"
+	}
+	if err := g.CheckEvents("VIC", "EntryBoss", append(past, other...), nil, now); err != nil {
+		t.Errorf("Expected other sources and past events to be ignored, got %v", err)
+	}
+}
+
+func TestGuardCheckClubs(t *testing.T) {
+	var existing []Club
+	for i := 0; i < 20; i++ {
+		existing = append(existing, Club{ClubName: "Club", ClubURL: string(rune('a' + i)), Source: "EntryBoss", LastSeen: "2025-07-01T00:00:00Z"})
+	}
+	// Clubs not seen in the last run do not count
+	existing = append(existing, Club{ClubName: "Gone", Source: "EntryBoss", LastSeen: "2024-01-01T00:00:00Z"})
+
+	g := DefaultGuard()
+	if err := g.CheckClubs("EntryBoss", existing, existing[:12]); err != nil {
+		t.Errorf("Expected 40%% drop to pass, got %v", err)
+	}
+	if err := g.CheckClubs("EntryBoss", existing, nil); err == nil {
+		t.Error("Expected losing every club to trip the guard")
+	}
+}
+
+func TestUpdaterRefusesSuspiciousDrop(t *testing.T) {
+	store := NewStore(t.TempDir())
+	clubs := []Club{{ClubName: "Busy Club", ClubURL: "https://entryboss.cc/calendar/busy", State: "VIC", Source: "EntryBoss"}}
+	if err := store.SaveClubs(clubs); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().AddDate(0, 1, 0).Format("2006-01-02") + "T00:00:00Z"
+	var existing []Event
+	for i := 0; i < 12; i++ {
+		existing = append(existing, Event{EventName: "Race", EventDate: future, ClubName: "Busy Club", State: "VIC", Source: "EntryBoss"})
+	}
+	if err := store.SaveEvents("VIC", existing); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &fakeSource{results: map[string]*Result{"VIC": {}}}
+	u := &Updater{Store: store, Guard: DefaultGuard()}
+
+	err := u.UpdateEvents(context.Background(), src, []string{"VIC"})
+	var shrinkage *ShrinkageError
+	if !errors.As(err, &shrinkage) {
+		t.Fatalf("Expected ShrinkageError, got %v", err)
+	}
+	if events, _ := store.LoadEvents("VIC"); len(events) != 12 {
+		t.Errorf("Events file was overwritten: %d events", len(events))
+	}
+
+	u.Guard.Force = true
+	if err := u.UpdateEvents(context.Background(), src, []string{"VIC"}); err != nil {
+		t.Fatalf("Forced update failed: %v", err)
+	}
+	if events, _ := store.LoadEvents("VIC"); len(events) != 0 {
+		t.Errorf("Forced update did not write: %d events", len(events))
+	}
+}
+
+func TestTimeZones(t *testing.T) {
+	for _, state := range States {
+		if TimeZone(state) == "" {
+			t.Errorf("No time zone for %s", state)
+		}
+		if Location(state) == time.UTC {
+			t.Errorf("Time zone for %s did not load", state)
+		}
+	}
+	if Location("XX") != time.UTC {
+		t.Error("Unknown state should fall back to UTC")
+	}
+}
+
+func TestCutoff(t *testing.T) {
+	now := time.Date(2025, 7, 1, 15, 30, 0, 0, time.UTC)
+
+	// 01:30 on 2 July in Sydney, 23:30 on 1 July in Perth
+	if got := Cutoff("NSW", now); got != "2025-07-01" {
+		t.Errorf("Cutoff(NSW) = %q, want %q", got, "2025-07-01")
+	}
+	if got := Cutoff("WA", now); got != "2025-06-30" {
+		t.Errorf("Cutoff(WA) = %q, want %q", got, "2025-06-30")
+	}
+}
+
+func TestEventStart(t *testing.T) {
+	event := Event{EventDate: "2025-07-05T00:00:00Z", StartTime: "08:30", State: "WA"}
+	start, ok := event.Start()
+	if !ok {
+		t.Fatal("Start failed")
+	}
+	if want := time.Date(2025, 7, 5, 0, 30, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Start = %v, want %v", start, want)
+	}
+
+	event = Event{EventDate: "2025-07-05T00:00:00Z", TimeZone: "Australia/Adelaide"}
+	start, _ = event.Start()
+	if want := time.Date(2025, 7, 4, 14, 30, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Start without time = %v, want %v", start, want)
+	}
+
+	if _, ok := (Event{EventDate: "TBC"}).Start(); ok {
+		t.Error("Start should fail for an unparseable date")
+	}
+}
+
+func TestSlug(t *testing.T) {
+	testCases := map[string]string{
+		"Northern Combine CC":      "northern-combine-cc",
+		"  St. Kilda CC (Racing) ": "st-kilda-cc-racing",
+		"Road Race":                "road-race",
+		"":                         "",
+	}
+	for input, want := range testCases {
+		if got := Slug(input); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCompareEvents(t *testing.T) {
+	now := time.Date(2025, 7, 1, 6, 0, 0, 0, time.UTC)
+	previous := []Event{
+		{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1", State: "VIC"},
+		{EventName: "Road Race", EventDate: "2025-07-06T00:00:00Z", EventURL: "https://entryboss.cc/races/2", State: "VIC"},
+		{EventName: "Cancelled Race", EventDate: "2025-07-07T00:00:00Z", EventURL: "https://entryboss.cc/races/3", State: "VIC"},
+		{EventName: "Finished Race", EventDate: "2025-06-20T00:00:00Z", EventURL: "https://entryboss.cc/races/4", State: "VIC"},
+	}
+	current := []Event{
+		{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1", State: "VIC"},
+		{EventName: "Road Race (Rescheduled)", EventDate: "2025-07-13T00:00:00Z", EventURL: "https://entryboss.cc/races/2", State: "VIC"},
+		{EventName: "New Race", EventDate: "2025-07-20T00:00:00Z", EventURL: "https://entryboss.cc/races/5", State: "VIC"},
+	}
+
+	changes := CompareEvents(previous, current, now)
+
+	if len(changes.Added) != 1 || changes.Added[0].EventName != "New Race" {
+		t.Errorf("Added = %+v, want New Race", changes.Added)
+	}
+	// The finished race dropped out on its own and is not a removal
+	if len(changes.Removed) != 1 || changes.Removed[0].EventName != "Cancelled Race" {
+		t.Errorf("Removed = %+v, want Cancelled Race", changes.Removed)
+	}
+	if len(changes.Moved) != 1 || changes.Moved[0].After.EventDate != "2025-07-13T00:00:00Z" {
+		t.Errorf("Moved = %+v, want the road race", changes.Moved)
+	}
+	if len(changes.Renamed) != 1 || changes.Renamed[0].Before.EventName != "Road Race" {
+		t.Errorf("Renamed = %+v, want the road race", changes.Renamed)
+	}
+
+	changes.State = "VIC"
+	var md strings.Builder
+	if err := (Changelog{Source: "EntryBoss", States: []StateChanges{changes}}).WriteMarkdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"**VIC**: 1 added, 1 removed, 1 date moved, 1 renamed, 0 changed",
+		"- Moved: Road Race (Rescheduled) from 2025-07-06 to 2025-07-13",
+		"- Renamed: Road Race to Road Race (Rescheduled) (2025-07-13)",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Markdown missing %q:\n%s", want, md.String())
+		}
+	}
+}
+
+func TestCompareEventsFoldedListing(t *testing.T) {
+	now := time.Date(2025, 7, 1, 6, 0, 0, 0, time.UTC)
+	entryBoss := Event{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1", State: "VIC", Source: "EntryBoss"}
+	buncheur := Event{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://www.buncheur.com/club-crit", State: "VIC", Source: "Buncheur"}
+
+	folded := entryBoss
+	folded.Listings = []Event{buncheur}
+	changes := CompareEvents([]Event{entryBoss, buncheur}, []Event{folded}, now)
+
+	if !changes.Empty() {
+		t.Errorf("Folding a listing = %+v, want no changes", changes)
+	}
+}
+
+func TestUpdateEventsRecordsChanges(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if err := store.SaveClubs([]Club{{ClubName: "Test Club", State: "VIC"}}); err != nil {
+		t.Fatal(err)
+	}
+	src := &fakeSource{results: map[string]*Result{"VIC": {Events: []Event{
+		{EventName: "Club Crit", EventDate: time.Now().AddDate(0, 0, 7).Format("2006-01-02") + "T00:00:00Z", EventURL: "https://entryboss.cc/races/1", State: "VIC", Source: "EntryBoss"},
+	}}}}
+
+	var md strings.Builder
+	u := &Updater{Store: store, RecordChanges: true, Markdown: &md}
+	if err := u.UpdateEvents(context.Background(), src, []string{"VIC"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(store.Path(ChangesDir))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one changelog, got %v (%v)", files, err)
+	}
+	if !strings.Contains(md.String(), "- Added: Club Crit") {
+		t.Errorf("Unexpected Markdown:\n%s", md.String())
+	}
+
+	// A run that changes nothing records nothing
+	if err := os.RemoveAll(store.Path(ChangesDir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.UpdateEvents(context.Background(), src, []string{"VIC"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.Path(ChangesDir)); err == nil {
+		t.Errorf("Did not expect a changelog for an unchanged run")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	previous := []Club{
+		{ClubName: "Northern Combine", ClubURL: "https://entryboss.cc/calendar/nc", State: "VIC", LastSeen: "2025-06-01T00:00:00Z"},
+		{ClubName: "Old Name CC", ClubURL: "https://entryboss.cc/calendar/old", State: "VIC"},
+		{ClubName: "Folded CC", ClubURL: "https://entryboss.cc/calendar/folded", State: "NSW"},
+	}
+	current := []Club{
+		{ClubName: "Northern Combine", ClubURL: "https://entryboss.cc/calendar/nc", State: "VIC", LastSeen: "2025-07-01T00:00:00Z"},
+		{ClubName: "New Name CC", ClubURL: "https://entryboss.cc/calendar/old", State: "VIC"},
+		{ClubName: "Fresh CC", ClubURL: "https://entryboss.cc/calendar/fresh", State: "QLD"},
+	}
+
+	clubs := CompareClubs(previous, current)
+	if len(clubs.Added) != 1 || len(clubs.Removed) != 1 || len(clubs.Changed) != 1 {
+		t.Fatalf("Expected one added, removed and renamed club, got %+v", clubs)
+	}
+
+	// A club whose URL was backfilled is the same club, by its ID
+	backfilled := CompareClubs(
+		[]Club{{ID: "vic-test-club", ClubName: "Test Club", ClubURL: "https://www.buncheur.com/test-club-road-race", State: "VIC", Source: "Buncheur"}},
+		[]Club{{ID: "vic-test-club", ClubName: "Test Club", ClubURL: "https://www.buncheur.com/clubs/test-club", State: "VIC", Source: "Buncheur"}},
+	)
+	if len(backfilled.Added) != 0 || len(backfilled.Removed) != 0 || len(backfilled.Changed) != 1 {
+		t.Errorf("Backfilled URL = %+v, want one changed club", backfilled)
+	}
+
+	venue := Event{EventName: "Club Crit", EventDate: "2025-07-05T00:00:00Z", EventURL: "https://entryboss.cc/races/1", State: "VIC", Venue: "Old Track"}
+	moved := venue
+	moved.Venue = "New Track"
+	events := CompareEvents([]Event{venue}, []Event{moved}, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC))
+	events.State = "VIC"
+
+	var text strings.Builder
+	if err := (Diff{Events: []StateChanges{events}, Clubs: clubs}).WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"VIC: 0 added, 0 removed, 0 date moved, 0 renamed, 1 changed",
+		"  Changed venue: Club Crit (2025-07-05)",
+		"All states: 1 added, 1 removed, 1 changed",
+		"  Changed: Old Name CC (VIC) to New Name CC (VIC)",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Text missing %q:\n%s", want, text.String())
+		}
+	}
+
+	text.Reset()
+	if err := (Diff{}).WriteText(&text); err != nil || text.String() != "No changes\n" {
+		t.Errorf("Empty diff = %q, %v", text.String(), err)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	entryBoss := Event{EventName: "2025 Winter Criterium Series - Round 3", EventDate: "2025-07-05T00:00:00Z", ClubName: "Manly Warringah Cycling Club", EventURL: "https://entryboss.cc/races/1", Source: "EntryBoss", FirstSeen: "2025-06-01T00:00:00Z"}
+	buncheur := Event{EventName: "Winter Criterium Series Round 3", EventDate: "2025-07-05T00:00:00Z", ClubName: "Manly Warringah CC", EventURL: "https://www.buncheur.com/mwcc-crit-3", Source: "Buncheur", FirstSeen: "2025-06-10T00:00:00Z"}
+	otherRound := Event{EventName: "Winter Criterium Series Round 4", EventDate: "2025-07-05T00:00:00Z", ClubName: "Manly Warringah CC", EventURL: "https://www.buncheur.com/mwcc-crit-4", Source: "Buncheur"}
+	sameSource := Event{EventName: "Winter Criterium Series - Round 3 (Juniors)", EventDate: "2025-07-05T00:00:00Z", ClubName: "Manly Warringah Cycling Club", EventURL: "https://entryboss.cc/races/2", Source: "EntryBoss"}
+	otherClub := Event{EventName: "Winter Criterium Series Round 3", EventDate: "2025-07-05T00:00:00Z", ClubName: "Illawarra CC", EventURL: "https://www.buncheur.com/icc-crit-3", Source: "Buncheur"}
+
+	deduped, matches := Dedupe([]Event{entryBoss, buncheur, otherRound, sameSource, otherClub})
+
+	if len(matches) != 1 || matches[0].Canonical.EventURL != entryBoss.EventURL || matches[0].Duplicate.EventURL != buncheur.EventURL {
+		t.Fatalf("Expected the EntryBoss and Buncheur round 3 to match, got %+v", matches)
+	}
+	if len(deduped) != 4 {
+		t.Fatalf("Expected 4 events after dedupe, got %d", len(deduped))
+	}
+	for _, e := range deduped {
+		if e.EventURL == entryBoss.EventURL && (len(e.Listings) != 1 || e.Listings[0].EventURL != buncheur.EventURL) {
+			t.Errorf("Expected the Buncheur listing on the kept record, got %+v", e.Listings)
+		}
+		if e.EventURL == buncheur.EventURL {
+			t.Errorf("The Buncheur duplicate should have been folded in")
+		}
+	}
+
+	// Expanding gives back every event, and deduping again is stable
+	if expanded := Expand(deduped); len(expanded) != 5 {
+		t.Errorf("Expected 5 events after Expand, got %d", len(expanded))
+	}
+	again, _ := Dedupe(Expand(deduped))
+	if !reflect.DeepEqual(again, deduped) {
+		t.Errorf("Dedupe is not stable:\n got %+v\nwant %+v", again, deduped)
+	}
+}
+
+func TestUpdateEventsKeepsListingsAcrossRuns(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if err := store.SaveClubs([]Club{{ClubName: "Test Club", State: "NSW"}}); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().AddDate(0, 0, 7).Format("2006-01-02") + "T00:00:00Z"
+	listed := Event{EventName: "Club Crit", EventDate: date, ClubName: "Test Club", EventURL: "https://www.buncheur.com/crit", State: "NSW", Source: "Buncheur"}
+	if err := store.SaveEvents("NSW", []Event{listed}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &fakeSource{results: map[string]*Result{"NSW": {Events: []Event{
+		{EventName: "Club Crit", EventDate: date, ClubName: "Test Club CC", EventURL: "https://entryboss.cc/races/1", State: "NSW", Source: "EntryBoss"},
+	}}}}
+	u := &Updater{Store: store}
+	for run := 0; run < 2; run++ {
+		if err := u.UpdateEvents(context.Background(), src, []string{"NSW"}); err != nil {
+			t.Fatal(err)
+		}
+		events, _ := store.LoadEvents("NSW")
+		if len(events) != 1 || len(events[0].Listings) != 1 {
+			t.Fatalf("Run %d: expected one record listed by both sources, got %+v", run+1, events)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	existing := []Club{
+		{ClubName: "Brunswick Cycling Club", ClubURL: "https://entryboss.cc/calendar/brunswick", State: "VIC", Source: "EntryBoss"},
+		{ClubName: "Bankstown Sports CC", ClubURL: "https://entryboss.cc/calendar/bscc", State: "NSW", Source: "EntryBoss"},
+		{ClubName: "Brunswick Cycling Club", ClubURL: "https://entryboss.cc/calendar/brunswick-nsw", State: "NSW", Source: "EntryBoss"},
+	}
+	aliases := []ClubAlias{{State: "NSW", Name: "Bankstown Sports CC", Aliases: []string{"BSCC"}}}
+	r := NewRegistry(existing, aliases)
+
+	merge := r.Link([]Club{
+		{ClubName: "Brunswick CC", State: "VIC", Source: "Buncheur", Identifiers: map[string]string{"Buncheur": "brunswick-cc"}},
+		{ClubName: "BSCC", State: "NSW", Source: "Buncheur", Identifiers: map[string]string{"Buncheur": "bscc"}},
+		{ClubName: "Fresh CC", State: "QLD", Source: "Buncheur", Identifiers: map[string]string{"Buncheur": "fresh-cc"}},
+	})
+	if len(merge.Added) != 1 || merge.Updated != 2 {
+		t.Errorf("Expected 1 club added and 2 linked, got %v and %d", merge.Added, merge.Updated)
+	}
+
+	byID := make(map[string]Club)
+	for _, c := range merge.Clubs {
+		byID[c.ID] = c
+	}
+	brunswick := byID["vic-brunswick-cycling-club"]
+	if brunswick.Identifier("Buncheur") != "brunswick-cc" || brunswick.Identifier("EntryBoss") != "https://entryboss.cc/calendar/brunswick" {
+		t.Errorf("Brunswick not linked to both sources: %+v", brunswick)
+	}
+	if len(brunswick.Aliases) != 1 || brunswick.Aliases[0] != "Brunswick CC" {
+		t.Errorf("Expected Buncheur's name as an alias, got %v", brunswick.Aliases)
+	}
+	if byID["nsw-bankstown-sports-cc"].Identifier("Buncheur") != "bscc" {
+		t.Errorf("Alias table not used: %+v", byID["nsw-bankstown-sports-cc"])
+	}
+	if _, ok := byID["nsw-brunswick-cycling-club"]; !ok {
+		t.Errorf("Expected IDs to include the state, got %v", byID)
+	}
+
+	// Linking again changes nothing, and the Buncheur club is now found by
+	// its slug even under another name
+	again := NewRegistry(merge.Clubs, aliases)
+	if m := again.Link([]Club{{ClubName: "Brunswick Cyclists", State: "VIC", Identifiers: map[string]string{"Buncheur": "brunswick-cc"}}}); len(m.Added) != 0 {
+		t.Errorf("Expected the club to be found by its Buncheur slug, got %v", m.Added)
+	}
+}
+
+func TestRegistryFold(t *testing.T) {
+	r := NewRegistry([]Club{
+		{ID: "vic-northern", ClubName: "Northern Combine", ClubURL: "https://entryboss.cc/calendar/nc", State: "VIC", Source: "EntryBoss"},
+		{ID: "vic-ncc", ClubName: "Northern Combine Cycling", ClubURL: "https://www.buncheur.com/ncc", State: "VIC", Source: "Buncheur", Identifiers: map[string]string{"Buncheur": "ncc"}},
+		{ID: "vic-other", ClubName: "Other Club", ClubURL: "https://entryboss.cc/calendar/other", State: "VIC", Source: "EntryBoss"},
+	}, nil)
+
+	if groups := r.Duplicates(); len(groups) != 1 || !reflect.DeepEqual(groups[0], []string{"vic-northern", "vic-ncc"}) {
+		t.Fatalf("Expected Northern Combine's two records as duplicates, got %v", groups)
+	}
+
+	club, err := r.Fold("vic-northern", "vic-ncc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if club.Identifier("Buncheur") != "ncc" || len(r.Clubs()) != 2 {
+		t.Errorf("Fold did not combine the clubs: %+v, %d clubs left", club, len(r.Clubs()))
+	}
+
+	if _, err := r.Fold("vic-northern", "vic-other"); err == nil {
+		t.Errorf("Expected clubs with different EntryBoss calendars not to fold")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		name, club, category string
+		want, reason         string
+	}{
+		{"Winter Criterium Series - Race 16", "Illawarra CC", "Criterium", "criterium", `category contains "crit*"`},
+		{"PMCC - Inaugural Gravel Race", "Port Macquarie CC", "Road Race", "gravel", `name contains "gravel"`},
+		{"Wednesday Night Gates", "Pine Rivers BMX Club", "", "bmx", `name contains "gates"`},
+		{"NBMX Gates 22/04/26", "Northern BMX", "", "bmx", `name contains "*bmx"`},
+		{"Coaching with Bella", "Maroondah BMX Club", "", "bmx", `club contains "bmx"`},
+		{"Twilight Racing", "Tamworth Mountain Bikers", "", "mtb", `club contains "mountain bik*"`},
+		{"Tuesday Night Track Racing - at DISC", "Brunswick Cycling Club", "", "track", `name contains "track"`},
+		{"GSCC Supervets - Age Adjusted Time Trial, 2 laps", "Geelong & Surfcoast Cycling Club", "", "time-trial", `name contains "time trial*"`},
+		{"Thursday Motorpacing [with Intro Session]", "Brunswick Cycling Club", "", "training", `name contains "motorpac*"`},
+		{"Dirt Girls Social Ride", "Brunswick Cycling Club", "", "social", `name contains "social*"`},
+		{"46th Benghazi Handicap", "Northern Cycling", "", "road", `name contains "handicap"`},
+		{"65th Bob Robson Memorial", "Footscray Cycling Club", "", "road", "no rule matched"},
+	}
+
+	rules := DefaultRules()
+	for _, tc := range testCases {
+		events := []Event{{EventName: tc.name, ClubName: tc.club, Category: tc.category}}
+		rules.Classify(events)
+		if events[0].Discipline != tc.want || events[0].DisciplineReason != tc.reason {
+			t.Errorf("Classify(%q, %q, %q) = %q (%s), want %q (%s)", tc.name, tc.club, tc.category,
+				events[0].Discipline, events[0].DisciplineReason, tc.want, tc.reason)
+		}
+	}
+}
+
+func TestClassifyTypes(t *testing.T) {
+	testCases := []struct {
+		name, category string
+		want, reason   string
+	}{
+		{"Coaching with Bodi", "", "coaching", `name contains "coaching"`},
+		{"Thursday Motorpacing [with Intro Session]", "", "training", `name contains "motorpac*"`},
+		{"NBMX Gates 22/04/26", "", "training", `name contains "gates"`},
+		{"ICC Wednesday Night 100 Lapper", "Training Session", "training", `category contains "training*"`},
+		{"Santos Tour Down Under Group Ride 17/01", "", "social", `name contains "group ride"`},
+		{"2026 Season Pass", "", "membership", `name contains "season pass"`},
+		{"GMBC Merch 2026 (Winter)", "", "merchandise", `name contains "merch*"`},
+		{"Gate Opening Systems - Mary and Harry Tams Handicap", "", "race", "no rule matched"},
+		{"Track race meeting", "", "race", "no rule matched"},
+		{"Night session crit", "", "race", "no rule matched"},
+		{"Monthly Club Meeting", "", "social", `name contains "club meeting"`},
+		{"Tuesday training session", "", "training", `name contains "training"`},
+		{"Nowra Velo Club Championships Criteriums (Club Members Only)", "Criterium", "race", "no rule matched"},
+	}
+
+	rules := DefaultRules()
+	for _, tc := range testCases {
+		events := []Event{{EventName: tc.name, Category: tc.category}}
+		rules.Classify(events)
+		if events[0].Type != tc.want || events[0].TypeReason != tc.reason {
+			t.Errorf("Classify(%q, %q) type = %q (%s), want %q (%s)", tc.name, tc.category,
+				events[0].Type, events[0].TypeReason, tc.want, tc.reason)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	rules, err := store.LoadRules()
+	if err != nil {
+		t.Fatalf("LoadRules failed: %v", err)
+	}
+	if len(rules.Disciplines) == 0 || rules.DefaultDiscipline != "road" {
+		t.Errorf("Expected the built-in rules without a rules file, got %+v", rules)
+	}
+
+	custom := `{"disciplines": [{"value": "track", "club": ["velo"]}]}`
+	if err := os.WriteFile(store.Path(RulesFile), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rules, err = store.LoadRules(); err != nil {
+		t.Fatalf("LoadRules failed: %v", err)
+	}
+
+	events := []Event{
+		{EventName: "Club Criterium", ClubName: "Nowra Velo", Listings: []Event{{EventName: "Club Crit", ClubName: "Nowra Velo Club"}}},
+		{EventName: "Club Criterium", ClubName: "Illawarra CC"},
+	}
+	rules.Classify(events)
+	if events[0].Discipline != "track" || events[0].Listings[0].Discipline != "track" {
+		t.Errorf("Custom rule not applied to the event and its listings: %+v", events[0])
+	}
+	if events[1].Discipline != "" || events[1].DisciplineReason != "" {
+		t.Errorf("Expected no discipline without a default, got %q (%s)", events[1].Discipline, events[1].DisciplineReason)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	testCases := []struct {
+		name, club string
+		drop       string
+	}{
+		{"Enter", "Test Club", `exact "enter"`},
+		{"Details", "Test Club", `exact "details"`},
+		{"2025 Season Pass", "Test Club", `contains "season pass"`},
+		{"Volunteer Sign-on", "Test Club", `contains "volunteer"`},
+		{"Jersey Pre-Order", "Test Club", `contains "pre-order"`},
+		{"Crit", "Test Club", "shorter than 5 characters"},
+		{"Winter Criterium", "Test Club", ""},
+	}
+
+	filters := DefaultFilters()
+	for _, tc := range testCases {
+		if got := filters.Drop(tc.name, tc.club, "VIC"); got != tc.drop {
+			t.Errorf("Drop(%q) = %q, want %q", tc.name, got, tc.drop)
+		}
+	}
+}
+
+func TestLoadFilters(t *testing.T) {
+	store := NewStore(t.TempDir())
+	custom := `{
+  "minLength": 5,
+  "exclude": {"contains": ["season pass"], "patterns": ["^round \\d+ entry$"]},
+  "include": {"exact": ["TT"]},
+  "clubs": [
+    {"club": "Knox BMX Club", "state": "VIC", "minLength": 3, "exclude": {"contains": ["gates"]}, "include": {"contains": ["season pass"]}}
+  ]
+}`
+	if err := os.WriteFile(store.Path(FiltersFile), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filters, err := store.LoadFilters()
+	if err != nil {
+		t.Fatalf("LoadFilters failed: %v", err)
+	}
+
+	testCases := []struct {
+		name, club, state string
+		drop              string
+	}{
+		{"Round 3 Entry", "Test Club", "VIC", `pattern "^round \\d+ entry$"`},
+		{"TT", "Test Club", "VIC", ""},
+		{"Crit", "Test Club", "VIC", "shorter than 5 characters"},
+		{"2026 Season Pass", "Test Club", "VIC", `contains "season pass"`},
+		// Knox BMX's own rules, matched by normalised name
+		{"2026 Season Pass", "Knox BMX", "VIC", ""},
+		{"Wednesday Gates", "Knox BMX", "VIC", `contains "gates" for Knox BMX Club`},
+		{"Rd 1", "Knox BMX", "VIC", ""},
+		{"Wednesday Gates", "Knox BMX", "NSW", ""},
+	}
+	for _, tc := range testCases {
+		if got := filters.Drop(tc.name, tc.club, tc.state); got != tc.drop {
+			t.Errorf("Drop(%q, %q, %q) = %q, want %q", tc.name, tc.club, tc.state, got, tc.drop)
+		}
+	}
+
+	var out strings.Builder
+	filters.Explain, filters.Log = true, log.New(&out, "", 0)
+	kept := filters.Filter([]Event{
+		{EventName: "Winter Criterium", ClubName: "Test Club", State: "VIC"},
+		{EventName: "2026 Season Pass", ClubName: "Test Club", State: "VIC", EventURL: "https://entryboss.cc/races/101"},
+	})
+	if len(kept) != 1 || kept[0].EventName != "Winter Criterium" {
+		t.Errorf("Filter kept %+v", kept)
+	}
+	if want := `Dropped "2026 Season Pass" (Test Club, VIC) https://entryboss.cc/races/101: contains "season pass"`; !strings.Contains(out.String(), want) {
+		t.Errorf("Explain logged %q, want %q", out.String(), want)
+	}
+
+	if err := os.WriteFile(store.Path(FiltersFile), []byte(`{"exclude": {"patterns": ["("]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.LoadFilters(); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestUpdateEventsAppliesFilters(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if err := store.SaveClubs([]Club{{ClubName: "Test Club", State: "VIC"}}); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().AddDate(0, 0, 7).Format("2006-01-02") + "T00:00:00Z"
+	src := &fakeSource{results: map[string]*Result{"VIC": {Events: []Event{
+		{EventName: "Club Crit", EventDate: date, EventURL: "https://www.buncheur.com/crit", State: "VIC", Source: "EntryBoss"},
+		{EventName: "Volunteer Sign-on", EventDate: date, EventURL: "https://www.buncheur.com/volunteer", State: "VIC", Source: "EntryBoss"},
+	}}}}
+
+	u := &Updater{Store: store, Filters: DefaultFilters()}
+	if err := u.UpdateEvents(context.Background(), src, []string{"VIC"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.LoadEvents("VIC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventName != "Club Crit" {
+		t.Errorf("Expected the volunteer sign-on to be dropped, got %+v", events)
+	}
+}
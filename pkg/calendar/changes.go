@@ -0,0 +1,227 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChangesDir is the directory inside a Store that holds a changelog of each
+// update run.
+const ChangesDir = "changes"
+
+// Changelog records what one update run changed in the events files.
+type Changelog struct {
+	Source string         `json:"source"`
+	Time   string         `json:"time"` // RFC 3339
+	States []StateChanges `json:"states"`
+}
+
+// StateChanges are the changes to one state's events, matched by EventID.
+type StateChanges struct {
+	State   string        `json:"state"`
+	Added   []Event       `json:"added,omitempty"`
+	Removed []Event       `json:"removed,omitempty"`
+	Moved   []EventChange `json:"dateMoved,omitempty"`
+	Renamed []EventChange `json:"renamed,omitempty"`
+	Changed []EventChange `json:"changed,omitempty"` // other details, such as venue or status
+}
+
+// EventChange is an event before and after a run. An event both moved and
+// renamed is listed under both.
+type EventChange struct {
+	Before Event `json:"before"`
+	After  Event `json:"after"`
+}
+
+// CompareEvents returns the changes from previous to current. Events that
+// dropped out because they have finished are not reported as removed. Each
+// source's listing of an event is compared on its own, so one folded into
+// another source's record by Dedupe is not reported as removed.
+func CompareEvents(previous, current []Event, now time.Time) StateChanges {
+	var changes StateChanges
+	previous, current = Expand(previous), Expand(current)
+
+	before := make(map[string]Event, len(previous))
+	for _, e := range previous {
+		before[EventID(e)] = e
+	}
+
+	after := make(map[string]bool, len(current))
+	for _, e := range current {
+		id := EventID(e)
+		after[id] = true
+
+		old, known := before[id]
+		if !known {
+			changes.Added = append(changes.Added, e)
+			continue
+		}
+		if day(old.EventDate) != day(e.EventDate) || day(old.EndDate) != day(e.EndDate) {
+			changes.Moved = append(changes.Moved, EventChange{Before: old, After: e})
+		}
+		if old.EventName != e.EventName {
+			changes.Renamed = append(changes.Renamed, EventChange{Before: old, After: e})
+		}
+		if len(otherFields(old, e)) > 0 {
+			changes.Changed = append(changes.Changed, EventChange{Before: old, After: e})
+		}
+	}
+
+	for _, e := range previous {
+		if after[EventID(e)] {
+			continue
+		}
+		if last := e.LastDate(); last != "" && last < Cutoff(e.State, now) {
+			continue
+		}
+		changes.Removed = append(changes.Removed, e)
+	}
+	return changes
+}
+
+// otherFields are the changed fields of an event other than its name and
+// dates, which are reported as renamed and moved.
+func otherFields(a, b Event) []string {
+	var fields []string
+	for _, field := range changedFields(a, b) {
+		if field != "name" && field != "date" && field != "end date" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Empty reports whether nothing changed.
+func (c StateChanges) Empty() bool {
+	return len(c.Added)+len(c.Removed)+len(c.Moved)+len(c.Renamed)+len(c.Changed) == 0
+}
+
+// Empty reports whether no state changed.
+func (c Changelog) Empty() bool {
+	for _, s := range c.States {
+		if !s.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteMarkdown writes the changelog as a Markdown list, suitable for a
+// commit message.
+func (c Changelog) WriteMarkdown(w io.Writer) error {
+	cw := &changeWriter{markdown: true}
+	cw.heading(c.Source)
+	for _, s := range c.States {
+		cw.stateChanges(s)
+	}
+	return cw.flush(w)
+}
+
+// changeWriter formats changes as plain text or Markdown.
+type changeWriter struct {
+	b        strings.Builder
+	markdown bool
+}
+
+func (cw *changeWriter) heading(title string) {
+	if cw.markdown {
+		fmt.Fprintf(&cw.b, "### %s\n", title)
+		return
+	}
+	fmt.Fprintf(&cw.b, "%s\n", title)
+}
+
+// section starts a group of items, e.g. "VIC: 1 added, 2 removed".
+func (cw *changeWriter) section(name, summary string) {
+	if cw.markdown {
+		fmt.Fprintf(&cw.b, "\n**%s**: %s\n\n", name, summary)
+		return
+	}
+	fmt.Fprintf(&cw.b, "\n%s: %s\n", name, summary)
+}
+
+func (cw *changeWriter) item(format string, args ...any) {
+	if cw.markdown {
+		cw.b.WriteString("- ")
+	} else {
+		cw.b.WriteString("  ")
+	}
+	fmt.Fprintf(&cw.b, format+"\n", args...)
+}
+
+func (cw *changeWriter) stateChanges(s StateChanges) {
+	if s.Empty() {
+		return
+	}
+	cw.section(s.State, fmt.Sprintf("%d added, %d removed, %d date moved, %d renamed, %d changed",
+		len(s.Added), len(s.Removed), len(s.Moved), len(s.Renamed), len(s.Changed)))
+	for _, e := range s.Added {
+		cw.item("Added: %s", describe(e))
+	}
+	for _, e := range s.Removed {
+		cw.item("Removed: %s", describe(e))
+	}
+	for _, ch := range s.Moved {
+		cw.item("Moved: %s from %s to %s", ch.After.EventName, dateRange(ch.Before), dateRange(ch.After))
+	}
+	for _, ch := range s.Renamed {
+		cw.item("Renamed: %s to %s", ch.Before.EventName, describe(ch.After))
+	}
+	for _, ch := range s.Changed {
+		cw.item("Changed %s: %s", strings.Join(otherFields(ch.Before, ch.After), ", "), describe(ch.After))
+	}
+}
+
+func (cw *changeWriter) flush(w io.Writer) error {
+	cw.b.WriteString("\n")
+	_, err := io.WriteString(w, cw.b.String())
+	return err
+}
+
+// describe formats an event as "Name (Club, 2025-07-05)".
+func describe(e Event) string {
+	if e.ClubName == "" {
+		return fmt.Sprintf("%s (%s)", e.EventName, dateRange(e))
+	}
+	return fmt.Sprintf("%s (%s, %s)", e.EventName, e.ClubName, dateRange(e))
+}
+
+func dateRange(e Event) string {
+	if end := day(e.EndDate); end != "" && end != day(e.EventDate) {
+		return day(e.EventDate) + " to " + end
+	}
+	return day(e.EventDate)
+}
+
+// day returns the "2006-01-02" part of a date.
+func day(date string) string {
+	if len(date) > 10 {
+		return date[:10]
+	}
+	return date
+}
+
+// SaveChangelog writes a changelog to the changes directory, named after its
+// time and source, e.g. changes/2025-07-01T060000Z-entryboss.json.
+func (s *Store) SaveChangelog(c Changelog) error {
+	stamp := strings.ReplaceAll(c.Time, ":", "")
+	name := filepath.Join(ChangesDir, stamp+"-"+Slug(c.Source)+".json")
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal changelog: %w", err)
+	}
+
+	if err := os.MkdirAll(s.Path(ChangesDir), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", ChangesDir, err)
+	}
+	if err := os.WriteFile(s.Path(name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
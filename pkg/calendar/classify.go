@@ -0,0 +1,161 @@
+package calendar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// RulesFile is the classification rules inside a Store. Without it the
+// built-in rules, in classify-rules.json in this package, apply.
+const RulesFile = "classify-rules.json"
+
+//go:embed classify-rules.json
+var defaultRules []byte
+
+// Rules classify events by discipline and by type, so races can be told from
+// training sessions, social rides and the like. For each, the first rule
+// matching an event decides; an event no rule matches gets the default.
+type Rules struct {
+	Disciplines       []Rule `json:"disciplines"`
+	DefaultDiscipline string `json:"defaultDiscipline"`
+
+	Types       []Rule `json:"types"`
+	DefaultType string `json:"defaultType"`
+}
+
+// Rule gives events the value Value when their source's category, their name
+// or their club's name contains one of its terms. Terms are words or phrases
+// matched against whole words, ignoring case and punctuation; a * at either
+// end also matches the rest of the word, so "crit*" matches "Criterium".
+type Rule struct {
+	Value    string   `json:"value"`
+	Category []string `json:"category,omitempty"`
+	Name     []string `json:"name,omitempty"`
+	Club     []string `json:"club,omitempty"`
+}
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() *Rules {
+	var rules Rules
+	if err := json.Unmarshal(defaultRules, &rules); err != nil {
+		panic(fmt.Sprintf("invalid built-in %s: %v", RulesFile, err))
+	}
+	return &rules
+}
+
+// LoadRules reads the classification rules. A missing file yields the
+// built-in rules.
+func (s *Store) LoadRules() (*Rules, error) {
+	data, err := os.ReadFile(s.Path(RulesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return DefaultRules(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", RulesFile, err)
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", RulesFile, err)
+	}
+	return &rules, nil
+}
+
+// Classify sets the Discipline and Type of each event and its listings, with
+// the reasons for them.
+func (r *Rules) Classify(events []Event) {
+	for i := range events {
+		r.classify(&events[i])
+		for j := range events[i].Listings {
+			r.classify(&events[i].Listings[j])
+		}
+	}
+}
+
+func (r *Rules) classify(e *Event) {
+	e.Discipline, e.DisciplineReason = classifyBy(r.Disciplines, r.DefaultDiscipline, *e)
+	e.Type, e.TypeReason = classifyBy(r.Types, r.DefaultType, *e)
+}
+
+// classifyBy returns the value of the first of rules matching e and the
+// reason, or else the default value.
+func classifyBy(rules []Rule, defaultValue string, e Event) (value, reason string) {
+	if value, reason, ok := firstMatch(rules, e); ok {
+		return value, reason
+	}
+	if defaultValue == "" {
+		return "", ""
+	}
+	return defaultValue, "no rule matched"
+}
+
+// firstMatch returns the value of the first rule matching e, and why it
+// matched, e.g. `name contains "crit"`.
+func firstMatch(rules []Rule, e Event) (value, reason string, ok bool) {
+	fields := []struct {
+		name  string
+		words []string
+	}{
+		{"category", wordsOf(e.Category)},
+		{"name", wordsOf(e.EventName)},
+		{"club", wordsOf(e.ClubName)},
+	}
+	for _, rule := range rules {
+		for _, field := range fields {
+			terms := rule.Name
+			switch field.name {
+			case "category":
+				terms = rule.Category
+			case "club":
+				terms = rule.Club
+			}
+			for _, term := range terms {
+				if containsTerm(field.words, term) {
+					return rule.Value, fmt.Sprintf("%s contains %q", field.name, term), true
+				}
+			}
+		}
+	}
+	return "", "", false
+}
+
+// containsTerm reports whether words contain the words of term in order. A *
+// at the start or end of term matches the rest of the first or last word.
+func containsTerm(words []string, term string) bool {
+	suffix := strings.HasPrefix(term, "*") // "*bmx" matches "NBMX"
+	prefix := strings.HasSuffix(term, "*") // "crit*" matches "criterium"
+	want := wordsOf(strings.Trim(term, "*"))
+	if len(want) == 0 {
+		return false
+	}
+
+	for i := 0; i+len(want) <= len(words); i++ {
+		matched := true
+		for j, w := range want {
+			word := words[i+j]
+			first, last := j == 0, j == len(want)-1
+			switch {
+			case first && last && suffix && prefix:
+				matched = strings.Contains(word, w)
+			case first && suffix:
+				matched = strings.HasSuffix(word, w)
+			case last && prefix:
+				matched = strings.HasPrefix(word, w)
+			default:
+				matched = word == w
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package calendar
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minNameSimilarity is how alike two event names must be, as the share of
+// their words in common, for events on the same day at the same club to be
+// taken as one.
+const minNameSimilarity = 0.5
+
+// Match is a pair of events from different sources found to be the same.
+type Match struct {
+	Canonical Event   // the record kept
+	Duplicate Event   // the record folded into it as a listing
+	Score     float64 // name similarity, from minNameSimilarity to 1
+}
+
+// Dedupe folds events listed by more than one source into one record. Events
+// match when they are from different sources and have the same date, the same
+// club once names are normalised, and similar names. The record first seen
+// is kept, with the others in its Listings; Dedupe is applied to the output
+// of Expand, so records are rebuilt from their listings on every run.
+func Dedupe(events []Event) ([]Event, []Match) {
+	type candidate struct {
+		i, j  int
+		score float64
+	}
+
+	// Only events on the same day at the same club can match
+	groups := make(map[string][]int)
+	for i, e := range events {
+		if e.Source == "" || len(e.EventDate) < 10 {
+			continue
+		}
+		key := e.EventDate[:10] + "|" + normaliseClub(e.ClubName)
+		groups[key] = append(groups[key], i)
+	}
+
+	var candidates []candidate
+	for _, group := range groups {
+		for x, i := range group {
+			for _, j := range group[x+1:] {
+				if events[i].Source == events[j].Source {
+					continue
+				}
+				if score := nameSimilarity(events[i].EventName, events[j].EventName); score >= minNameSimilarity {
+					candidates = append(candidates, candidate{i, j, score})
+				}
+			}
+		}
+	}
+
+	// Best matches first, so each event is paired with its closest listing
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].score != candidates[b].score {
+			return candidates[a].score > candidates[b].score
+		}
+		if candidates[a].i != candidates[b].i {
+			return candidates[a].i < candidates[b].i
+		}
+		return candidates[a].j < candidates[b].j
+	})
+
+	// canonical[i] is the index of the record event i is folded into, and
+	// sources[i] the sources already folded into event i
+	canonical := make(map[int]int)
+	sources := make(map[int]map[string]bool)
+
+	var matches []Match
+	for _, c := range candidates {
+		keep, fold := c.i, c.j
+		if preferred(events[fold], events[keep]) {
+			keep, fold = fold, keep
+		}
+		_, keepFolded := canonical[keep]
+		_, foldFolded := canonical[fold]
+		// A record takes at most one listing from each source, and a
+		// record with listings is not folded into another
+		if keepFolded || foldFolded || len(sources[fold]) > 0 || sources[keep][events[fold].Source] {
+			continue
+		}
+
+		if sources[keep] == nil {
+			sources[keep] = make(map[string]bool)
+		}
+		sources[keep][events[fold].Source] = true
+		canonical[fold] = keep
+		matches = append(matches, Match{Canonical: events[keep], Duplicate: events[fold], Score: c.score})
+	}
+
+	listings := make(map[int][]Event)
+	for fold, keep := range canonical {
+		listings[keep] = append(listings[keep], events[fold])
+	}
+
+	deduped := make([]Event, 0, len(events)-len(canonical))
+	for i, e := range events {
+		if _, folded := canonical[i]; folded {
+			continue
+		}
+		if l := listings[i]; len(l) > 0 {
+			sort.Slice(l, func(a, b int) bool { return l[a].Source < l[b].Source })
+			e.Listings = l
+		}
+		deduped = append(deduped, e)
+	}
+	SortEvents(deduped)
+	return deduped, matches
+}
+
+// Expand undoes Dedupe, returning every record and listing as an event of
+// its own, so that each source's events can be merged on their own.
+func Expand(events []Event) []Event {
+	expanded := make([]Event, 0, len(events))
+	for _, e := range events {
+		listings := e.Listings
+		e.Listings = nil
+		expanded = append(expanded, e)
+		for _, l := range listings {
+			l.Listings = nil
+			expanded = append(expanded, l)
+		}
+	}
+	return expanded
+}
+
+// Sources returns the sources listing an event, its own first.
+func (e Event) Sources() []string {
+	sources := []string{e.Source}
+	for _, l := range e.Listings {
+		sources = append(sources, l.Source)
+	}
+	return sources
+}
+
+// preferred reports whether a should be kept over b: the record seen first,
+// or else the one from the source that sorts first.
+func preferred(a, b Event) bool {
+	if a.FirstSeen != b.FirstSeen && a.FirstSeen != "" && b.FirstSeen != "" {
+		return a.FirstSeen < b.FirstSeen
+	}
+	return a.Source < b.Source
+}
+
+// clubNoise are the words that vary between sources' names for one club, as
+// in "Manly Warringah CC" and "Manly Warringah Cycling Club".
+var clubNoise = map[string]bool{
+	"the": true, "cycling": true, "cycle": true, "cyclists": true, "bicycle": true,
+	"club": true, "cc": true, "inc": true, "incorporated": true, "assoc": true, "association": true,
+}
+
+// normaliseClub reduces a club name to the words that identify it.
+func normaliseClub(name string) string {
+	all := wordsOf(name)
+	var kept []string
+	for _, w := range all {
+		if !clubNoise[w] {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		kept = all
+	}
+	return strings.Join(kept, " ")
+}
+
+// nameNoise are the words that say nothing about which event a name is.
+var nameNoise = map[string]bool{
+	"the": true, "and": true, "of": true, "at": true, "a": true,
+}
+
+// nameSimilarity returns the share of the words of two event names they have
+// in common (the Dice coefficient). Years are ignored, as one source often
+// leaves them out, but any other numbers, such as round numbers, must agree.
+func nameSimilarity(a, b string) float64 {
+	wordsA, numbersA := nameWords(a)
+	wordsB, numbersB := nameWords(b)
+	if len(numbersA) > 0 && len(numbersB) > 0 && !sameSet(numbersA, numbersB) {
+		return 0
+	}
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	common := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(wordsA)+len(wordsB))
+}
+
+// nameWords splits an event name into its words and its numbers other than
+// years.
+func nameWords(name string) (words, numbers map[string]bool) {
+	words, numbers = make(map[string]bool), make(map[string]bool)
+	for _, w := range wordsOf(name) {
+		switch {
+		case nameNoise[w]:
+		case isNumber(w) && len(w) == 4 && (strings.HasPrefix(w, "19") || strings.HasPrefix(w, "20")):
+		case isNumber(w):
+			numbers[strings.TrimLeft(w, "0")] = true
+		default:
+			words[w] = true
+		}
+	}
+	return words, numbers
+}
+
+// wordsOf splits a name into lowercase words of letters and digits.
+func wordsOf(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func sameSet(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Diff is the difference between two snapshots of the data files.
+type Diff struct {
+	Events []StateChanges `json:"events"` // states with changes only
+	Clubs  ClubChanges    `json:"clubs"`
+}
+
+// ClubChanges are the changes to clubs.json, matched by club ID.
+type ClubChanges struct {
+	Added   []Club       `json:"added,omitempty"`
+	Removed []Club       `json:"removed,omitempty"`
+	Changed []ClubChange `json:"changed,omitempty"` // renamed, moved state or new URL
+}
+
+// ClubChange is a club before and after.
+type ClubChange struct {
+	Before Club `json:"before"`
+	After  Club `json:"after"`
+}
+
+// CompareClubs returns the changes from previous to current. Clubs are
+// matched by ID, or for clubs without one by URL, or by state and name for
+// clubs without a URL either; a new lastSeen is not a change.
+func CompareClubs(previous, current []Club) ClubChanges {
+	var changes ClubChanges
+
+	key := func(c Club) string {
+		if c.ClubURL != "" {
+			return c.ClubURL
+		}
+		return c.State + "|" + strings.ToLower(c.ClubName)
+	}
+
+	byID := make(map[string]int)
+	byKey := make(map[string]int)
+	for i, c := range previous {
+		if c.ID != "" {
+			byID[c.ID] = i
+		}
+		byKey[key(c)] = i
+	}
+
+	matched := make(map[int]bool, len(previous))
+	for _, c := range current {
+		i, known := byID[c.ID]
+		if c.ID == "" || !known {
+			// Records from before clubs had IDs are matched the old way
+			if i, known = byKey[key(c)]; known && previous[i].ID != "" && c.ID != "" {
+				known = false
+			}
+		}
+		if known && matched[i] {
+			known = false
+		}
+
+		if !known {
+			changes.Added = append(changes.Added, c)
+			continue
+		}
+		matched[i] = true
+		if old := previous[i]; old.ClubName != c.ClubName || old.State != c.State || old.ClubURL != c.ClubURL {
+			changes.Changed = append(changes.Changed, ClubChange{Before: old, After: c})
+		}
+	}
+
+	for i, c := range previous {
+		if !matched[i] {
+			changes.Removed = append(changes.Removed, c)
+		}
+	}
+	return changes
+}
+
+// Empty reports whether no club changed.
+func (c ClubChanges) Empty() bool {
+	return len(c.Added)+len(c.Removed)+len(c.Changed) == 0
+}
+
+// Empty reports whether the snapshots are the same.
+func (d Diff) Empty() bool {
+	for _, s := range d.Events {
+		if !s.Empty() {
+			return false
+		}
+	}
+	return d.Clubs.Empty()
+}
+
+// WriteText writes the diff as plain text.
+func (d Diff) WriteText(w io.Writer) error {
+	return d.write(w, &changeWriter{})
+}
+
+// WriteMarkdown writes the diff as Markdown.
+func (d Diff) WriteMarkdown(w io.Writer) error {
+	return d.write(w, &changeWriter{markdown: true})
+}
+
+func (d Diff) write(w io.Writer, cw *changeWriter) error {
+	if d.Empty() {
+		cw.b.WriteString("No changes\n")
+		_, err := io.WriteString(w, cw.b.String())
+		return err
+	}
+
+	events := Diff{Events: d.Events}
+	if !events.Empty() {
+		cw.heading("Events")
+		for _, s := range d.Events {
+			cw.stateChanges(s)
+		}
+	}
+
+	if !d.Clubs.Empty() {
+		if !events.Empty() {
+			cw.b.WriteString("\n")
+		}
+		cw.heading("Clubs")
+		cw.section("All states", fmt.Sprintf("%d added, %d removed, %d changed",
+			len(d.Clubs.Added), len(d.Clubs.Removed), len(d.Clubs.Changed)))
+		for _, c := range d.Clubs.Added {
+			cw.item("Added: %s (%s)", c.ClubName, c.State)
+		}
+		for _, c := range d.Clubs.Removed {
+			cw.item("Removed: %s (%s)", c.ClubName, c.State)
+		}
+		for _, ch := range d.Clubs.Changed {
+			if ch.Before.ClubURL != ch.After.ClubURL {
+				cw.item("Changed: %s (%s) to %s (%s) at %s", ch.Before.ClubName, ch.Before.State, ch.After.ClubName, ch.After.State, ch.After.ClubURL)
+				continue
+			}
+			cw.item("Changed: %s (%s) to %s (%s)", ch.Before.ClubName, ch.Before.State, ch.After.ClubName, ch.After.State)
+		}
+	}
+	return cw.flush(w)
+}
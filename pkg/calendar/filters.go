@@ -0,0 +1,210 @@
+package calendar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// FiltersFile is the event filters inside a Store. Without it the built-in
+// filters, in event-filters.json in this package, apply.
+const FiltersFile = "event-filters.json"
+
+//go:embed event-filters.json
+var defaultFilters []byte
+
+// Filters decide which candidate events a source keeps, dropping links such as
+// "Enter" buttons, season passes and volunteer sign-ups. A name matching an
+// Include rule is always kept; otherwise names shorter than MinLength
+// characters or matching an Exclude rule are dropped. Rules for a club, in
+// Clubs, are checked before the global ones, and its MinLength replaces the
+// global one.
+type Filters struct {
+	MinLength int          `json:"minLength"`
+	Exclude   NameRules    `json:"exclude"`
+	Include   NameRules    `json:"include"`
+	Clubs     []ClubFilter `json:"clubs,omitempty"`
+
+	// Explain logs each dropped candidate, and the rule that dropped it, to
+	// Log.
+	Explain bool   `json:"-"`
+	Log     Logger `json:"-"`
+}
+
+// NameRules match event names, ignoring case: Exact names, names that contain
+// one of Contains, and names matching one of Patterns, regular expressions.
+type NameRules struct {
+	Exact    []string `json:"exact,omitempty"`
+	Contains []string `json:"contains,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+
+	patterns []*regexp.Regexp
+}
+
+// ClubFilter holds the rules for one club, named as in clubs.json; State
+// limits them to the club in that state.
+type ClubFilter struct {
+	Club      string    `json:"club"`
+	State     string    `json:"state,omitempty"`
+	MinLength *int      `json:"minLength,omitempty"`
+	Exclude   NameRules `json:"exclude"`
+	Include   NameRules `json:"include"`
+}
+
+// DefaultFilters returns the built-in filters.
+func DefaultFilters() *Filters {
+	f, err := parseFilters(defaultFilters)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in %s: %v", FiltersFile, err))
+	}
+	return f
+}
+
+// LoadFilters reads the event filters. A missing file yields the built-in
+// filters.
+func (s *Store) LoadFilters() (*Filters, error) {
+	data, err := os.ReadFile(s.Path(FiltersFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return DefaultFilters(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FiltersFile, err)
+	}
+
+	f, err := parseFilters(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FiltersFile, err)
+	}
+	return f, nil
+}
+
+func parseFilters(data []byte) (*Filters, error) {
+	var f Filters
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if err := f.Include.compile(); err != nil {
+		return nil, err
+	}
+	if err := f.Exclude.compile(); err != nil {
+		return nil, err
+	}
+	for i := range f.Clubs {
+		if err := f.Clubs[i].Include.compile(); err != nil {
+			return nil, err
+		}
+		if err := f.Clubs[i].Exclude.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+// Keep reports whether e passes the filters, logging the rule that dropped
+// it if Explain is set. A nil *Filters keeps everything.
+func (f *Filters) Keep(e Event) bool {
+	rule := f.Drop(e.EventName, e.ClubName, e.State)
+	if rule == "" {
+		return true
+	}
+	if f.Explain {
+		Logf(f.Log, "Dropped %q (%s, %s) %s: %s\n", e.EventName, e.ClubName, e.State, e.EventURL, rule)
+	}
+	return false
+}
+
+// Filter returns the events that pass the filters.
+func (f *Filters) Filter(events []Event) []Event {
+	if f == nil {
+		return events
+	}
+	kept := events[:0:0]
+	for _, e := range events {
+		if f.Keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// Drop returns the rule that drops an event named name at club in state, e.g.
+// `contains "season pass"`, or "" if it is kept.
+func (f *Filters) Drop(name, club, state string) string {
+	if f == nil {
+		return ""
+	}
+	name = strings.TrimSpace(name)
+
+	var clubs []ClubFilter
+	for _, c := range f.Clubs {
+		if normaliseClub(c.Club) == normaliseClub(club) && (c.State == "" || strings.EqualFold(c.State, state)) {
+			clubs = append(clubs, c)
+		}
+	}
+
+	for _, c := range clubs {
+		if c.Include.match(name) != "" {
+			return ""
+		}
+	}
+	if f.Include.match(name) != "" {
+		return ""
+	}
+
+	minLength := f.MinLength
+	for _, c := range clubs {
+		if c.MinLength != nil {
+			minLength = *c.MinLength
+		}
+	}
+	if utf8.RuneCountInString(name) < minLength {
+		return fmt.Sprintf("shorter than %d characters", minLength)
+	}
+
+	for _, c := range clubs {
+		if rule := c.Exclude.match(name); rule != "" {
+			return rule + " for " + c.Club
+		}
+	}
+	return f.Exclude.match(name)
+}
+
+// match returns the first rule matching name, or "".
+func (r *NameRules) match(name string) string {
+	lower := strings.ToLower(name)
+	for _, exact := range r.Exact {
+		if strings.EqualFold(strings.TrimSpace(exact), name) {
+			return fmt.Sprintf("exact %q", exact)
+		}
+	}
+	for _, sub := range r.Contains {
+		if sub != "" && strings.Contains(lower, strings.ToLower(sub)) {
+			return fmt.Sprintf("contains %q", sub)
+		}
+	}
+	for i, re := range r.patterns {
+		if re.MatchString(name) {
+			return fmt.Sprintf("pattern %q", r.Patterns[i])
+		}
+	}
+	return ""
+}
+
+// compile compiles the Patterns, ignoring case.
+func (r *NameRules) compile() error {
+	r.patterns = nil
+	for _, pattern := range r.Patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return nil
+}
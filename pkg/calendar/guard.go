@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Guard refuses to overwrite data files when a run produces suspiciously
+// less data than the previous one, which usually means a source changed its
+// markup or API rather than that clubs stopped listing events.
+type Guard struct {
+	// MaxDropPercent is the largest drop in upcoming events (or discovered
+	// clubs) from one source that is accepted without complaint.
+	MaxDropPercent float64
+
+	// MinCount is the previous count below which percentage drops are ignored,
+	// so a state with a handful of events can legitimately empty out.
+	MinCount int
+
+	// BusyClubEvents is the number of upcoming events at which a club counts as
+	// busy. A busy club dropping to zero events trips the guard.
+	BusyClubEvents int
+
+	// Force writes the files anyway; problems are logged as warnings.
+	Force bool
+}
+
+// DefaultGuard trips when a source loses more than half of its upcoming
+// events, or a club with five or more upcoming events loses them all.
+func DefaultGuard() *Guard {
+	return &Guard{MaxDropPercent: 50, MinCount: 10, BusyClubEvents: 5}
+}
+
+// ShrinkageError is returned when a Guard refuses to write a file.
+type ShrinkageError struct {
+	File     string
+	Problems []string
+}
+
+func (e *ShrinkageError) Error() string {
+	return fmt.Sprintf("suspicious drop in %s: %s", e.File, strings.Join(e.Problems, "; "))
+}
+
+// CheckEvents compares a source's upcoming events before and after a run. It
+// returns a *ShrinkageError if the drop looks suspicious, or nil.
+func (g *Guard) CheckEvents(state, source string, before, after []Event, now time.Time) error {
+	if g == nil {
+		return nil
+	}
+
+	cutoff := Cutoff(state, now)
+	beforeByClub := upcomingByClub(before, source, cutoff)
+	afterByClub := upcomingByClub(after, source, cutoff)
+
+	var problems []string
+	if p := g.checkDrop(total(beforeByClub), total(afterByClub), source+" events"); p != "" {
+		problems = append(problems, p)
+	}
+
+	var emptied []string
+	for club, count := range beforeByClub {
+		if g.BusyClubEvents > 0 && count >= g.BusyClubEvents && afterByClub[club] == 0 {
+			emptied = append(emptied, fmt.Sprintf("%s (%d)", club, count))
+		}
+	}
+	if len(emptied) > 0 {
+		sort.Strings(emptied)
+		problems = append(problems, fmt.Sprintf("busy clubs now have no events: %s", strings.Join(emptied, ", ")))
+	}
+
+	return g.result(EventsFile(state), problems)
+}
+
+// CheckClubs compares the clubs a source discovered in this run with those it
+// discovered in the previous run, identified by their latest lastSeen.
+func (g *Guard) CheckClubs(source string, existing, scraped []Club) error {
+	if g == nil {
+		return nil
+	}
+
+	lastRun := ""
+	for _, c := range existing {
+		if c.Source == source && c.LastSeen > lastRun {
+			lastRun = c.LastSeen
+		}
+	}
+	previous := 0
+	for _, c := range existing {
+		if c.Source == source && c.LastSeen == lastRun {
+			previous++
+		}
+	}
+
+	var problems []string
+	if p := g.checkDrop(previous, len(scraped), source+" clubs"); p != "" {
+		problems = append(problems, p)
+	}
+	return g.result(ClubsFile, problems)
+}
+
+func (g *Guard) checkDrop(before, after int, what string) string {
+	if before == 0 || before < g.MinCount || after >= before {
+		return ""
+	}
+	drop := float64(before-after) / float64(before) * 100
+	if drop <= g.MaxDropPercent {
+		return ""
+	}
+	return fmt.Sprintf("%s dropped from %d to %d (-%.0f%%, limit %.0f%%)", what, before, after, drop, g.MaxDropPercent)
+}
+
+func (g *Guard) result(file string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ShrinkageError{File: file, Problems: problems}
+}
+
+// upcomingByClub counts a source's events that end on or after cutoff, per club.
+func upcomingByClub(events []Event, source, cutoff string) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range events {
+		if last := e.LastDate(); e.Source != source || last == "" || last < cutoff {
+			continue
+		}
+		counts[e.ClubName]++
+	}
+	return counts
+}
+
+func total(counts map[string]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
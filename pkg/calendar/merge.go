@@ -0,0 +1,150 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+)
+
+// MergeEvents replaces every existing event from source with fresh, keeping
+// events from other sources untouched. The result is sorted by date.
+//
+// Events are matched with their previous version by EventID to maintain
+// FirstSeen, LastSeen and LastChanged. Stale events, which were not actually
+// seen, keep their LastSeen. Events saved before sources were recorded are
+// replaced by the fresh event with the same ID.
+func MergeEvents(existing []Event, source string, fresh []Event, now time.Time) []Event {
+	seen := now.Format(time.RFC3339)
+
+	previous := make(map[string]Event)
+	for _, e := range existing {
+		if e.Source == source || e.Source == "" {
+			previous[EventID(e)] = e
+		}
+	}
+
+	merged := make([]Event, 0, len(existing)+len(fresh))
+	replaced := make(map[string]bool)
+	for _, e := range fresh {
+		e.ID = EventID(e)
+		replaced[e.ID] = true
+
+		prior, known := previous[e.ID]
+		switch {
+		case !known:
+			e.FirstSeen, e.LastSeen, e.LastChanged = seen, seen, seen
+		default:
+			e.FirstSeen, e.LastSeen, e.LastChanged = prior.FirstSeen, prior.LastSeen, prior.LastChanged
+			if e.FirstSeen == "" {
+				e.FirstSeen = seen
+			}
+			if !e.Stale {
+				e.LastSeen = seen
+			}
+			if e.LastChanged == "" || !sameDetails(prior, e) {
+				e.LastChanged = seen
+			}
+		}
+		merged = append(merged, e)
+	}
+
+	for _, e := range existing {
+		if e.Source == source || (e.Source == "" && replaced[EventID(e)]) {
+			continue
+		}
+		merged = append(merged, e)
+	}
+
+	SortEvents(merged)
+	return merged
+}
+
+// sameDetails reports whether two versions of an event say the same thing,
+// ignoring the bookkeeping fields.
+func sameDetails(a, b Event) bool {
+	return len(changedFields(a, b)) == 0
+}
+
+// changedFields names the details that differ between two versions of an
+// event, in the order of the Event fields.
+func changedFields(a, b Event) []string {
+	var fields []string
+	for _, f := range []struct {
+		name       string
+		old, value string
+	}{
+		{"name", a.EventName, b.EventName},
+		{"date", a.EventDate, b.EventDate},
+		{"club", a.ClubName, b.ClubName},
+		{"state", a.State, b.State},
+		{"url", a.EventURL, b.EventURL},
+		{"category", a.Category, b.Category},
+		{"end date", a.EndDate, b.EndDate},
+		{"start time", a.StartTime, b.StartTime},
+		{"venue", a.Venue, b.Venue},
+		{"entries close", a.EntriesClose, b.EntriesClose},
+		{"grades", strings.Join(a.Grades, ", "), strings.Join(b.Grades, ", ")},
+		{"status", a.Status, b.Status},
+	} {
+		if f.old != f.value {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// CarryForward returns the existing events from source that belong to a
+// failed club and have not yet finished, marked stale, so that one failed
+// fetch does not wipe the club's calendar. Events that were already stale keep
+// their original StaleSince.
+func CarryForward(existing []Event, source string, failures []Failure, now time.Time) []Event {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	failed := make(map[string]bool)
+	for _, f := range failures {
+		failed[f.Club.ClubName+f.Club.State] = true
+	}
+
+	staleSince := now.Format(time.RFC3339)
+
+	var kept []Event
+	for _, e := range existing {
+		if e.Source != source || !failed[e.ClubName+e.State] {
+			continue
+		}
+		// Include events ending yesterday onwards, matching the scrapers
+		if last := e.LastDate(); last == "" || last < Cutoff(e.State, now) {
+			continue
+		}
+		if !e.Stale {
+			e.Stale = true
+			e.StaleSince = staleSince
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+// ClubMerge summarises the result of MergeClubs, AddClubs or the Registry
+// methods they use.
+type ClubMerge struct {
+	Clubs    []Club   // the merged, sorted club list
+	Added    []string // "Name (STATE)" for each club not seen before
+	Updated  int      // existing clubs refreshed or linked to another source
+	Migrated int      // existing clubs given a missing lastSeen or source
+}
+
+// MergeClubs folds freshly discovered clubs into the existing list; see
+// Registry.Merge. Clubs missing from the scrape are preserved.
+func MergeClubs(existing, scraped []Club, source, now string) ClubMerge {
+	return NewRegistry(existing, nil).Merge(scraped, source, now)
+}
+
+// AddClubs appends the clubs not already registered and returns the new list
+// along with the number added; see Registry.Link. Existing clubs only gain
+// the identifiers of sources that newly list them.
+func AddClubs(existing, clubs []Club) ([]Club, int) {
+	merge := NewRegistry(existing, nil).Link(clubs)
+	return merge.Clubs, len(merge.Added)
+}
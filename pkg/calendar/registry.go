@@ -0,0 +1,406 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AliasesFile is the alias table inside a Store: other names clubs go by,
+// for variants that normalising their names does not catch.
+const AliasesFile = "club-aliases.json"
+
+// ClubAlias gives the other names of a club in a state, e.g. "BSCC" for
+// "Bankstown Sports CC".
+type ClubAlias struct {
+	State   string   `json:"state"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// LoadAliases reads the alias table. A missing file yields no aliases.
+func (s *Store) LoadAliases() ([]ClubAlias, error) {
+	data, err := os.ReadFile(s.Path(AliasesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", AliasesFile, err)
+	}
+
+	var aliases []ClubAlias
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", AliasesFile, err)
+	}
+	return aliases, nil
+}
+
+// Identifier returns the club's identifier on source: its EntryBoss calendar
+// URL or its Buncheur club slug, or "" if the source does not list it. Clubs
+// saved before identifiers were recorded are identified by their URL, or by
+// their name on Buncheur, which names its pages after clubs.
+func (c Club) Identifier(source string) string {
+	if len(c.Identifiers) > 0 {
+		return c.Identifiers[source]
+	}
+
+	// Clubs without a source were all found on EntryBoss
+	legacy := c.Source
+	if legacy == "" {
+		legacy = "EntryBoss"
+	}
+	switch {
+	case source != legacy:
+		return ""
+	case source == "Buncheur":
+		return Slug(c.ClubName)
+	default:
+		return c.ClubURL
+	}
+}
+
+// Registry is the canonical club list. Every club has a stable ID, and a club
+// reported by a source is recognised by its identifier on that source or, the
+// first time that source reports it, by its name in its state. Names are
+// compared once normalised ("Brunswick CC" is "Brunswick Cycling Club") and
+// through the alias table and each club's own Aliases.
+type Registry struct {
+	clubs   []Club
+	aliases map[string]string // nameKey of an alias to that of its club
+}
+
+// NewRegistry returns a registry of clubs, giving any club without an ID or
+// identifiers one.
+func NewRegistry(clubs []Club, aliases []ClubAlias) *Registry {
+	r := &Registry{aliases: make(map[string]string)}
+	for _, a := range aliases {
+		for _, alias := range a.Aliases {
+			r.aliases[nameKey(a.State, alias)] = nameKey(a.State, a.Name)
+		}
+	}
+	for _, c := range clubs {
+		r.add(c)
+	}
+	return r
+}
+
+// Clubs returns the registered clubs, sorted by state and name.
+func (r *Registry) Clubs() []Club {
+	clubs := append([]Club(nil), r.clubs...)
+	SortClubs(clubs)
+	return clubs
+}
+
+// Merge folds freshly discovered clubs from source into the registry. A club
+// recognised by its identifier takes the scraped name and state; one
+// recognised by name gains the source's identifier and, if the source spells
+// it differently, the name as an alias. Every scraped club, new or not, is
+// last seen at now, so that a run's clubs share one timestamp. Existing clubs
+// without a lastSeen or source are given now and source respectively.
+func (r *Registry) Merge(scraped []Club, source, now string) ClubMerge {
+	var result ClubMerge
+	for _, c := range scraped {
+		if c.Source == "" {
+			c.Source = source
+		}
+		c.LastSeen = now
+		i, byIdentifier := r.find(c)
+		switch {
+		case i < 0:
+			r.add(c)
+			result.Added = append(result.Added, fmt.Sprintf("%s (%s)", c.ClubName, c.State))
+			continue
+		case byIdentifier:
+			existing := &r.clubs[i]
+			if existing.ClubName != c.ClubName {
+				existing.Aliases = addName(existing.Aliases, existing.ClubName)
+				existing.ClubName = c.ClubName
+			}
+			existing.State = c.State
+		default:
+			r.link(i, c)
+		}
+		r.clubs[i].LastSeen = now
+		result.Updated++
+	}
+
+	// Handle migration: set lastSeen and source for existing clubs that don't have them
+	for i := range r.clubs {
+		club := &r.clubs[i]
+		if club.LastSeen != "" && club.Source != "" {
+			continue
+		}
+		if club.LastSeen == "" {
+			club.LastSeen = now
+		}
+		if club.Source == "" {
+			club.Source = source
+		}
+		result.Migrated++
+	}
+
+	result.Clubs = r.Clubs()
+	return result
+}
+
+// Link adds the clubs that are not registered yet, and gives registered clubs
+// found under another source that source's identifier. Their names and other
+// details are left alone, except that a club first found on the same source
+// takes the URL it now reports.
+func (r *Registry) Link(found []Club) ClubMerge {
+	var result ClubMerge
+	for _, c := range found {
+		i, _ := r.find(c)
+		if i < 0 {
+			r.add(c)
+			result.Added = append(result.Added, fmt.Sprintf("%s (%s)", c.ClubName, c.State))
+			continue
+		}
+		changed := r.link(i, c)
+		if existing := &r.clubs[i]; c.Source != "" && existing.Source == c.Source && c.ClubURL != "" && existing.ClubURL != c.ClubURL {
+			existing.ClubURL = c.ClubURL
+			changed = true
+		}
+		if changed {
+			result.Updated++
+		}
+	}
+	result.Clubs = r.Clubs()
+	return result
+}
+
+// Duplicates returns the IDs of registered clubs that look like one club:
+// the same normalised or aliased name in a state, without conflicting
+// identifiers. The first ID of each group is the club that was registered
+// first.
+func (r *Registry) Duplicates() [][]string {
+	var groups [][]string
+	grouped := make(map[int]bool)
+	for i := range r.clubs {
+		if grouped[i] {
+			continue
+		}
+		group := []string{r.clubs[i].ID}
+		merged := r.clubs[i]
+		for j := i + 1; j < len(r.clubs); j++ {
+			if grouped[j] || !r.sameName(merged, r.clubs[j]) || conflicting(merged, r.clubs[j]) {
+				continue
+			}
+			grouped[j] = true
+			group = append(group, r.clubs[j].ID)
+			merged = mergeClub(merged, r.clubs[j])
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Fold merges the clubs with the given IDs into the club with the first one:
+// it gains their identifiers, and their names as aliases. Clubs listed under
+// different identifiers on the same source cannot be folded together.
+func (r *Registry) Fold(ids ...string) (Club, error) {
+	if len(ids) < 2 {
+		return Club{}, fmt.Errorf("need at least two clubs to merge")
+	}
+
+	into := r.index(ids[0])
+	if into < 0 {
+		return Club{}, fmt.Errorf("no club with ID %q", ids[0])
+	}
+	club := r.clubs[into]
+
+	folded := make(map[int]bool)
+	for _, id := range ids[1:] {
+		i := r.index(id)
+		if i < 0 {
+			return Club{}, fmt.Errorf("no club with ID %q", id)
+		}
+		if i == into || folded[i] {
+			continue
+		}
+		if conflicting(club, r.clubs[i]) {
+			return Club{}, fmt.Errorf("%s and %s are different clubs on the same source", club.ID, id)
+		}
+		club = mergeClub(club, r.clubs[i])
+		folded[i] = true
+	}
+
+	r.clubs[into] = club
+	kept := r.clubs[:0]
+	for i, c := range r.clubs {
+		if !folded[i] {
+			kept = append(kept, c)
+		}
+	}
+	r.clubs = kept
+	return club, nil
+}
+
+// mergeClub returns club with other folded into it.
+func mergeClub(club, other Club) Club {
+	identifiers := make(map[string]string)
+	for source, id := range club.Identifiers {
+		identifiers[source] = id
+	}
+	for source, id := range other.Identifiers {
+		if _, ok := identifiers[source]; !ok {
+			identifiers[source] = id
+		}
+	}
+	club.Identifiers = identifiers
+
+	aliases := append([]string(nil), club.Aliases...)
+	for _, name := range append([]string{other.ClubName}, other.Aliases...) {
+		if !strings.EqualFold(name, club.ClubName) {
+			aliases = addName(aliases, name)
+		}
+	}
+	club.Aliases = aliases
+
+	if other.LastSeen > club.LastSeen {
+		club.LastSeen = other.LastSeen
+	}
+	return club
+}
+
+// find returns the index of the registered club c is, or -1, and whether it
+// was recognised by an identifier rather than by name.
+func (r *Registry) find(c Club) (int, bool) {
+	c = identified(c)
+	for source, id := range c.Identifiers {
+		// A club's URL is its own wherever it is, but clubs in different
+		// states can share a name, and so a slug
+		anyState := strings.Contains(id, "/")
+		for i := range r.clubs {
+			if r.clubs[i].Identifiers[source] == id && (anyState || strings.EqualFold(r.clubs[i].State, c.State)) {
+				return i, true
+			}
+		}
+	}
+	for i := range r.clubs {
+		if r.sameName(r.clubs[i], c) && !conflicting(r.clubs[i], c) {
+			return i, false
+		}
+	}
+	return -1, false
+}
+
+// link gives the club at i the identifiers of c it lacks, and c's name as an
+// alias. It reports whether the club changed.
+func (r *Registry) link(i int, c Club) bool {
+	before := len(r.clubs[i].Identifiers) + len(r.clubs[i].Aliases)
+	r.clubs[i] = mergeClub(r.clubs[i], identified(c))
+	return len(r.clubs[i].Identifiers)+len(r.clubs[i].Aliases) != before
+}
+
+// add registers a club, giving it an ID and identifiers if it has none.
+func (r *Registry) add(c Club) {
+	c = identified(c)
+	if c.ID == "" || r.index(c.ID) >= 0 {
+		c.ID = r.newID(c)
+	}
+	r.clubs = append(r.clubs, c)
+}
+
+func (r *Registry) index(id string) int {
+	for i := range r.clubs {
+		if r.clubs[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// newID derives an ID from the club's state and name, e.g.
+// "vic-brunswick-cycling-club", numbered if another club already has it.
+func (r *Registry) newID(c Club) string {
+	base := Slug(c.State + " " + c.ClubName)
+	id := base
+	for n := 2; r.index(id) >= 0; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// sameName reports whether two clubs in the same state share a name or
+// alias.
+func (r *Registry) sameName(a, b Club) bool {
+	if !strings.EqualFold(a.State, b.State) {
+		return false
+	}
+	names := make(map[string]bool)
+	for _, name := range append([]string{a.ClubName}, a.Aliases...) {
+		names[r.key(a.State, name)] = true
+	}
+	for _, name := range append([]string{b.ClubName}, b.Aliases...) {
+		if names[r.key(b.State, name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// key is the name a club is compared by: normalised, and resolved through
+// the alias table.
+func (r *Registry) key(state, name string) string {
+	key := nameKey(state, name)
+	if canonical, ok := r.aliases[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+func nameKey(state, name string) string {
+	return strings.ToUpper(state) + "|" + normaliseClub(name)
+}
+
+// conflicting reports whether two clubs have different identifiers on the
+// same source, which makes them different clubs whatever their names.
+func conflicting(a, b Club) bool {
+	for source, id := range identified(b).Identifiers {
+		if other := identified(a).Identifiers[source]; other != "" && other != id {
+			return true
+		}
+	}
+	return false
+}
+
+// identified returns c with its Identifiers filled in from its URL or name
+// if it has none. The map is copied, so c can be changed safely.
+func identified(c Club) Club {
+	identifiers := make(map[string]string)
+	for source, id := range c.Identifiers {
+		identifiers[source] = id
+	}
+	if len(identifiers) == 0 {
+		source := c.Source
+		if source == "" {
+			source = "EntryBoss"
+		}
+		if id := c.Identifier(source); id != "" {
+			identifiers[source] = id
+		}
+	}
+	c.Identifiers = identifiers
+	return c
+}
+
+// addName appends name to names unless it is already there, ignoring case,
+// and keeps them sorted.
+func addName(names []string, name string) []string {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return names
+		}
+	}
+	names = append(append([]string(nil), names...), name)
+	sort.Strings(names)
+	return names
+}
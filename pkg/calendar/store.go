@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ClubsFile is the name of the club list inside a Store.
+const ClubsFile = "clubs.json"
+
+// EventsFile returns the name of the events file for a state, e.g. events-vic.json.
+func EventsFile(state string) string {
+	return fmt.Sprintf("events-%s.json", strings.ToLower(state))
+}
+
+// Slug turns a name into a lowercase file name: "Northern Combine CC" becomes
+// "northern-combine-cc".
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// Store reads and writes clubs.json and the per-state events files in a directory.
+type Store struct {
+	Dir string
+}
+
+// NewStore returns a Store rooted at dir.
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Path returns the full path of a file inside the store.
+func (s *Store) Path(name string) string {
+	return filepath.Join(s.Dir, name)
+}
+
+// LoadClubs reads clubs.json. A missing file is reported as an error wrapping
+// fs.ErrNotExist.
+func (s *Store) LoadClubs() ([]Club, error) {
+	data, err := os.ReadFile(s.Path(ClubsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ClubsFile, err)
+	}
+
+	var clubs []Club
+	if err := json.Unmarshal(data, &clubs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ClubsFile, err)
+	}
+	return clubs, nil
+}
+
+// SaveClubs sorts clubs by state and name and writes them to clubs.json.
+func (s *Store) SaveClubs(clubs []Club) error {
+	SortClubs(clubs)
+
+	data, err := json.MarshalIndent(clubs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal clubs: %w", err)
+	}
+
+	if err := os.WriteFile(s.Path(ClubsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ClubsFile, err)
+	}
+	return nil
+}
+
+// LoadEvents reads the events file for a state. A missing file yields no
+// events and no error.
+func (s *Store) LoadEvents(state string) ([]Event, error) {
+	name := EventsFile(state)
+	data, err := os.ReadFile(s.Path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return events, nil
+}
+
+// SaveEvents sorts events by date and writes them to the state's events file.
+func (s *Store) SaveEvents(state string, events []Event) error {
+	SortEvents(events)
+
+	name := EventsFile(state)
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal events for %s: %w", state, err)
+	}
+
+	if err := os.WriteFile(s.Path(name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// SortClubs orders clubs by state, then name.
+func SortClubs(clubs []Club) {
+	sort.SliceStable(clubs, func(i, j int) bool {
+		if clubs[i].State != clubs[j].State {
+			return clubs[i].State < clubs[j].State
+		}
+		return clubs[i].ClubName < clubs[j].ClubName
+	})
+}
+
+// SortEvents orders events by date.
+func SortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventDate < events[j].EventDate
+	})
+}
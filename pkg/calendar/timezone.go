@@ -0,0 +1,72 @@
+package calendar
+
+import (
+	"time"
+
+	// Embed the zone database so Australian zones resolve on any host
+	_ "time/tzdata"
+)
+
+// stateTimeZones maps each state to the IANA zone used for its events.
+var stateTimeZones = map[string]string{
+	"ACT": "Australia/Sydney",
+	"NSW": "Australia/Sydney",
+	"NT":  "Australia/Darwin",
+	"QLD": "Australia/Brisbane",
+	"SA":  "Australia/Adelaide",
+	"TAS": "Australia/Hobart",
+	"VIC": "Australia/Melbourne",
+	"WA":  "Australia/Perth",
+}
+
+// TimeZone returns the IANA zone name for a state, or "" for an unknown state.
+func TimeZone(state string) string {
+	return stateTimeZones[state]
+}
+
+// Location returns the time zone of a state, falling back to UTC.
+func Location(state string) *time.Location {
+	return loadLocation(TimeZone(state))
+}
+
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Cutoff returns the earliest event date, as "2006-01-02", that still counts
+// as upcoming in a state at time now: yesterday in the state's local time.
+func Cutoff(state string, now time.Time) string {
+	return now.In(Location(state)).AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// Start returns the moment the event starts: its date and StartTime in its
+// TimeZone (or its state's zone). ok is false if EventDate cannot be parsed.
+// Without a StartTime the event is taken to start at local midnight.
+func (e Event) Start() (start time.Time, ok bool) {
+	if len(e.EventDate) < 10 {
+		return time.Time{}, false
+	}
+
+	loc := loadLocation(e.TimeZone)
+	if e.TimeZone == "" {
+		loc = Location(e.State)
+	}
+
+	value, layout := e.EventDate[:10], "2006-01-02"
+	if e.StartTime != "" {
+		value, layout = value+" "+e.StartTime, "2006-01-02 15:04"
+	}
+
+	start, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return start, true
+}
@@ -0,0 +1,320 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// Updater runs a Source against a Store: it fetches clubs or events, merges
+// them with what is already on disk and writes the result back.
+type Updater struct {
+	Store *Store
+	Log   Logger
+
+	// Guard, if set, stops files being overwritten with suspiciously little data.
+	Guard *Guard
+
+	// Filters, if set, drop fetched events that are not events at all, such
+	// as season passes, whichever source they come from.
+	Filters *Filters
+
+	// RecordChanges saves a Changelog of each UpdateEvents run that changed
+	// anything to the changes directory, and Markdown, if set, receives the
+	// same changelog as Markdown.
+	RecordChanges bool
+	Markdown      io.Writer
+}
+
+// UpdateClubs discovers the source's clubs and merges them into clubs.json.
+func (u *Updater) UpdateClubs(ctx context.Context, src Source) error {
+	var existingClubs []Club
+	if clubs, err := u.Store.LoadClubs(); err == nil {
+		existingClubs = clubs
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		Logf(u.Log, "Warning: %v\n", err)
+	}
+
+	scraped, err := src.DiscoverClubs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := u.guard(u.Guard.CheckClubs(src.Name(), existingClubs, scraped)); err != nil {
+		return err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	merge := u.registry(existingClubs).Merge(scraped, src.Name(), now)
+
+	if err := u.Store.SaveClubs(merge.Clubs); err != nil {
+		return err
+	}
+
+	stateStats := make(map[string]int)
+	for _, club := range scraped {
+		stateStats[club.State]++
+	}
+
+	Logf(u.Log, "\nClub update summary:\n")
+	Logf(u.Log, "  Total clubs: %d\n", len(merge.Clubs))
+	Logf(u.Log, "  New clubs found: %d\n", len(merge.Added))
+	sort.Strings(merge.Added)
+	for _, name := range merge.Added {
+		Logf(u.Log, "    - %s\n", name)
+	}
+	Logf(u.Log, "  Existing clubs updated: %d\n", merge.Updated)
+	if merge.Migrated > 0 {
+		Logf(u.Log, "  Clubs migrated (added lastSeen): %d\n", merge.Migrated)
+	}
+	Logf(u.Log, "  Clubs preserved from previous runs: %d\n", len(merge.Clubs)-len(merge.Added)-merge.Updated)
+
+	Logf(u.Log, "\nClubs by state:\n")
+	for _, state := range States {
+		if count, exists := stateStats[state]; exists {
+			Logf(u.Log, "  %s: %d clubs\n", state, count)
+		}
+	}
+
+	return nil
+}
+
+// UpdateEvents fetches events from src for each state and replaces that
+// source's events in the state's events file, classifying them by the store's
+// Rules. Clubs reported by the source are added to clubs.json.
+func (u *Updater) UpdateEvents(ctx context.Context, src Source, states []string) error {
+	allClubs, err := u.Store.LoadClubs()
+	if err != nil {
+		return err
+	}
+	rules, err := u.Store.LoadRules()
+	if err != nil {
+		return err
+	}
+
+	totalEvents := 0
+	stateResults := make(map[string]int)
+	var foundClubs []Club
+	var failures []Failure
+	var invalid []Invalid
+	var refused []error
+	changelog := Changelog{Source: src.Name(), Time: time.Now().UTC().Format(time.RFC3339)}
+
+	for stateIndex, stateCode := range states {
+		if len(states) > 1 {
+			Logf(u.Log, "\n=== Processing %s (%d/%d) ===\n", stateCode, stateIndex+1, len(states))
+		}
+
+		var stateClubs []Club
+		for _, club := range allClubs {
+			if club.State == stateCode {
+				stateClubs = append(stateClubs, club)
+			}
+		}
+
+		result, err := src.FetchEvents(ctx, stateCode, stateClubs)
+		if errors.Is(err, ErrNoClubs) {
+			Logf(u.Log, "No clubs found for state %s, skipping...\n", stateCode)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s events for %s: %w", src.Name(), stateCode, err)
+		}
+		result.Events = u.Filters.Filter(result.Events)
+
+		stored, err := u.Store.LoadEvents(stateCode)
+		if err != nil {
+			Logf(u.Log, "Warning: %v\n", err)
+		}
+		// Merge each source's events on their own, then fold duplicates
+		// listed by several sources together again
+		existing := Expand(stored)
+
+		// Keep the last known events of clubs that could not be fetched
+		now := time.Now()
+		carried := CarryForward(existing, src.Name(), result.Failures, now)
+		fresh := append(result.Events, carried...)
+
+		merged := MergeEvents(existing, src.Name(), fresh, now)
+		if err := u.guard(u.Guard.CheckEvents(stateCode, src.Name(), existing, merged, now)); err != nil {
+			u.logFailures(stateCode, result.Failures)
+			refused = append(refused, err)
+			continue
+		}
+		// Classify every event again, so changes to the rules apply to
+		// events already known
+		rules.Classify(merged)
+		deduped, matches := Dedupe(merged)
+		if err := u.Store.SaveEvents(stateCode, deduped); err != nil {
+			return err
+		}
+
+		Logf(u.Log, "Updated %s with %d %s events (Total: %d)\n", EventsFile(stateCode), len(result.Events), src.Name(), len(deduped))
+		u.logMatches(stateCode, matches)
+		changes := CompareEvents(stored, deduped, now)
+		changes.State = stateCode
+		if !changes.Empty() {
+			Logf(u.Log, "Changes in %s: %d added, %d removed, %d date moved, %d renamed, %d changed\n",
+				stateCode, len(changes.Added), len(changes.Removed), len(changes.Moved), len(changes.Renamed), len(changes.Changed))
+			changelog.States = append(changelog.States, changes)
+		}
+		u.logFailures(stateCode, result.Failures)
+		u.logInvalid(stateCode, result.Invalid)
+		if len(carried) > 0 {
+			Logf(u.Log, "Kept %d previous events from failed clubs in %s, marked stale\n", len(carried), stateCode)
+		}
+
+		totalEvents += len(result.Events)
+		stateResults[stateCode] = len(result.Events)
+		foundClubs = append(foundClubs, result.Clubs...)
+		failures = append(failures, result.Failures...)
+		invalid = append(invalid, result.Invalid...)
+	}
+
+	if err := u.recordChanges(changelog); err != nil {
+		Logf(u.Log, "Warning: failed to record changes: %v\n", err)
+	}
+
+	if len(foundClubs) > 0 {
+		if err := u.addClubs(allClubs, foundClubs, src.Name()); err != nil {
+			Logf(u.Log, "Warning: failed to sync clubs: %v\n", err)
+		}
+	}
+
+	// Print summary if multiple states were processed
+	if len(states) > 1 {
+		Logf(u.Log, "\n=== Summary ===\n")
+		Logf(u.Log, "Total %s events: %d\n", src.Name(), totalEvents)
+		Logf(u.Log, "\nEvents by state:\n")
+		for _, stateCode := range states {
+			if count, exists := stateResults[stateCode]; exists {
+				Logf(u.Log, "  %s: %d events\n", stateCode, count)
+			}
+		}
+		if len(failures) > 0 {
+			permanent := countPermanent(failures)
+			Logf(u.Log, "\nFailed clubs: %d (%d permanent, %d transient)\n", len(failures), permanent, len(failures)-permanent)
+		}
+		if len(invalid) > 0 {
+			Logf(u.Log, "Invalid records skipped: %d\n", len(invalid))
+		}
+	}
+
+	return errors.Join(refused...)
+}
+
+// guard passes through a Guard check result. When the guard is forced the
+// problem is logged and nil returned; otherwise it is logged as a refusal.
+func (u *Updater) guard(err error) error {
+	var shrinkage *ShrinkageError
+	if !errors.As(err, &shrinkage) {
+		return err
+	}
+
+	if u.Guard.Force {
+		Logf(u.Log, "Warning: %v (writing anyway because of --force)\n", err)
+		return nil
+	}
+
+	Logf(u.Log, "Refusing to overwrite %s:\n", shrinkage.File)
+	for _, problem := range shrinkage.Problems {
+		Logf(u.Log, "  - %s\n", problem)
+	}
+	return err
+}
+
+// logFailures reports the clubs in a state whose events could not be fetched,
+// separating permanent failures (such as a removed calendar) from transient ones.
+func (u *Updater) logFailures(state string, failures []Failure) {
+	if len(failures) == 0 {
+		return
+	}
+
+	permanent := countPermanent(failures)
+	Logf(u.Log, "Failed to fetch %d clubs in %s (%d permanent, %d transient):\n", len(failures), state, permanent, len(failures)-permanent)
+	for _, f := range failures {
+		kind := "transient"
+		if f.Permanent {
+			kind = "permanent"
+		}
+		Logf(u.Log, "  - %s [%s]: %v\n", f.Club.ClubName, kind, f.Err)
+	}
+}
+
+// logInvalid reports the records in a state the source returned but could
+// not turn into events.
+func (u *Updater) logInvalid(state string, invalid []Invalid) {
+	if len(invalid) == 0 {
+		return
+	}
+
+	Logf(u.Log, "Skipped %d invalid records in %s:\n", len(invalid), state)
+	for _, r := range invalid {
+		Logf(u.Log, "  - %s: %v\n", r.Record, r.Err)
+	}
+}
+
+// logMatches reports the events found listed by more than one source.
+func (u *Updater) logMatches(state string, matches []Match) {
+	if len(matches) == 0 {
+		return
+	}
+
+	Logf(u.Log, "Merged %d events listed by more than one source in %s:\n", len(matches), state)
+	for _, m := range matches {
+		Logf(u.Log, "  - %s (%s) = %s (%s), %s, %s\n", m.Canonical.EventName, m.Canonical.Source,
+			m.Duplicate.EventName, m.Duplicate.Source, m.Canonical.ClubName, day(m.Canonical.EventDate))
+	}
+}
+
+func countPermanent(failures []Failure) int {
+	n := 0
+	for _, f := range failures {
+		if f.Permanent {
+			n++
+		}
+	}
+	return n
+}
+
+func (u *Updater) recordChanges(changelog Changelog) error {
+	if changelog.Empty() {
+		return nil
+	}
+	if u.RecordChanges {
+		if err := u.Store.SaveChangelog(changelog); err != nil {
+			return err
+		}
+	}
+	if u.Markdown != nil {
+		return changelog.WriteMarkdown(u.Markdown)
+	}
+	return nil
+}
+
+func (u *Updater) addClubs(existing, found []Club, source string) error {
+	merge := u.registry(existing).Link(found)
+	if len(merge.Added) == 0 && merge.Updated == 0 {
+		return nil
+	}
+	if len(merge.Added) > 0 {
+		Logf(u.Log, "Added %d new clubs from %s\n", len(merge.Added), source)
+	}
+	if merge.Updated > 0 {
+		Logf(u.Log, "Linked %d existing clubs to %s\n", merge.Updated, source)
+	}
+	return u.Store.SaveClubs(merge.Clubs)
+}
+
+// registry returns a Registry of clubs using the store's alias table.
+func (u *Updater) registry(clubs []Club) *Registry {
+	aliases, err := u.Store.LoadAliases()
+	if err != nil {
+		Logf(u.Log, "Warning: %v\n", err)
+	}
+	return NewRegistry(clubs, aliases)
+}
@@ -0,0 +1,456 @@
+// Package dates finds dates, date ranges and times of day in the free text of
+// club and race pages, such as "Sat 5th July", "05/07/2025", "5–7 Jul 2025"
+// or "7:30am". A date without a year is taken as its next occurrence after
+// the time of the scrape, or up to a week before it, unless it names a weekday:
+// then the year in which the date falls on that weekday wins, even if it is
+// last year's, so "Sat 5 July" read in 2026 is 5 July 2025. Such dates are
+// in the past and are dropped by the callers' cutoffs.
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is how dates are formatted for events: midnight UTC on the day.
+const Layout = "2006-01-02T15:04:05Z"
+
+// MaxRangeDays bounds the length of a date range, so that unrelated numbers
+// either side of a dash are not taken for a range.
+const MaxRangeDays = 31
+
+// Match is a date or date range found in text.
+type Match struct {
+	// Start is the date, or the first day of a range, at midnight UTC. End
+	// is the last day of a range, and zero for a single date.
+	Start, End time.Time
+
+	// Index is where the match starts in the text, and Text what it matched.
+	Index int
+	Text  string
+}
+
+// Parts of the patterns below. Weekdays and months may be abbreviated, and
+// days may have ordinals, e.g. "Sat 5th", "Thurs 1st" or "Sept". A year is a
+// whole four-digit word, so "5 Jul 1500m" has none.
+const (
+	weekdayPart = `(mon(?:day)?|tue(?:s|sday)?|wed(?:nesday)?|thu(?:r|rs|rsday)?|fri(?:day)?|sat(?:urday)?|sun(?:day)?)\b\.?`
+	monthPart   = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\b\.?`
+	dayPart     = `(\d{1,2})(?:st|nd|rd|th)?\b`
+	yearPart    = `(?:,?\s+(\d{4})\b)?`
+)
+
+// The kinds of date found, by the pattern that found them.
+const (
+	dayMonth    = iota // "Sat, 5 Jul 2025", "Sunday 13th of July"
+	monthDay           // "July 5th, 2025", "Mar 15 2025"
+	isoDate            // "2025-07-05"
+	numericDate        // "05/07/2025" or "5.7.25", day first
+	dayOnly            // "Fri 10 –", the start of a range ending "12 Oct"
+)
+
+// patterns find each kind of date. The groups are the weekday, day, month
+// and year for dayMonth; the weekday, month, day and year for monthDay; the
+// year, month and day for isoDate; the day, month and year for numericDate;
+// and the weekday and day for dayOnly.
+var patterns = map[int]*regexp.Regexp{
+	dayMonth:    regexp.MustCompile(`(?i)\b(?:` + weekdayPart + `,?\s+(?:the\s+)?)?` + dayPart + `(?:\s+of)?\s+` + monthPart + yearPart),
+	monthDay:    regexp.MustCompile(`(?i)\b(?:` + weekdayPart + `,?\s+)?` + monthPart + `\s+(?:the\s+)?` + dayPart + yearPart),
+	isoDate:     regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})`),
+	numericDate: regexp.MustCompile(`\b(\d{1,2})[/.](\d{1,2})[/.](\d{4}|\d{2})\b`),
+	dayOnly:     regexp.MustCompile(`(?i)\b(?:` + weekdayPart + `,?\s+)?` + dayPart + rangeSeparator),
+}
+
+// anchored match each kind of full date at the start of text, for the end of
+// a range.
+var anchored = map[int]*regexp.Regexp{
+	dayMonth:    regexp.MustCompile(`^(?:` + patterns[dayMonth].String() + `)`),
+	monthDay:    regexp.MustCompile(`^(?:` + patterns[monthDay].String() + `)`),
+	isoDate:     regexp.MustCompile(`^(?:` + patterns[isoDate].String() + `)`),
+	numericDate: regexp.MustCompile(`^(?:` + patterns[numericDate].String() + `)`),
+}
+
+const rangeSeparator = `\s*(?:[-–—]|\b(?:to|until|till|thru|through)\b)\s*`
+
+var (
+	separatorPattern = regexp.MustCompile(`(?i)^` + rangeSeparator)
+
+	// endDayPattern matches the end of a range such as "July 5–7, 2025",
+	// which gives only its day and year.
+	endDayPattern = regexp.MustCompile(`(?i)^` + dayPart + yearPart)
+)
+
+// date is a date as written, before its year is known. Year is 0 if the text
+// does not give it.
+type date struct {
+	year    int
+	month   time.Month
+	day     int
+	weekday string
+}
+
+// Find returns the first date or date range in text. now is when the text
+// was read, by which missing years are inferred.
+func Find(text string, now time.Time) (Match, bool) {
+	matches := FindAll(text, now, 1)
+	if len(matches) == 0 {
+		return Match{}, false
+	}
+	return matches[0], true
+}
+
+// FindAll returns successive dates and date ranges in text, at most n of
+// them, or all if n is negative.
+func FindAll(text string, now time.Time, n int) []Match {
+	type candidate struct {
+		kind int
+		loc  []int
+	}
+	var candidates []candidate
+	for kind, re := range patterns {
+		for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+			candidates = append(candidates, candidate{kind, loc})
+		}
+	}
+	// Earliest first, and the longest of those starting together
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i].loc, candidates[j].loc
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[1] != b[1] {
+			return a[1] > b[1]
+		}
+		return candidates[i].kind < candidates[j].kind
+	})
+
+	var matches []Match
+	next := 0
+	for _, c := range candidates {
+		if n >= 0 && len(matches) >= n {
+			break
+		}
+		if c.loc[0] < next {
+			continue
+		}
+		if m, ok := match(text, c.kind, c.loc, now); ok {
+			matches = append(matches, m)
+			next = m.Index + len(m.Text)
+		}
+	}
+	return matches
+}
+
+// match reads the date a pattern found at loc, and the range it starts, if
+// any.
+func match(text string, kind int, loc []int, now time.Time) (Match, bool) {
+	if kind == dayOnly {
+		return matchDayOnly(text, loc, now)
+	}
+
+	start, ok := parse(kind, groups(text, loc))
+	if !ok {
+		return Match{}, false
+	}
+	m := Match{Index: loc[0], Text: text[loc[0]:loc[1]]}
+
+	if sep := separatorPattern.FindStringIndex(text[loc[1]:]); sep != nil {
+		rest := text[loc[1]+sep[1]:]
+		for _, endKind := range []int{dayMonth, monthDay, isoDate, numericDate} {
+			endLoc := anchored[endKind].FindStringSubmatchIndex(rest)
+			if endLoc == nil {
+				continue
+			}
+			if end, ok := parse(endKind, groups(rest, endLoc)); ok {
+				if first, last, ok := resolveRange(start, end, now); ok {
+					m.Start, m.End = first, last
+					m.Text = text[loc[0] : loc[1]+sep[1]+endLoc[1]]
+					return m, true
+				}
+			}
+		}
+		if kind == monthDay {
+			if endLoc := endDayPattern.FindStringSubmatchIndex(rest); endLoc != nil {
+				g := groups(rest, endLoc)
+				end := date{month: start.month, day: atoi(g[1]), year: atoi(g[2])}
+				if first, last, ok := resolveRange(start, end, now); ok {
+					m.Start, m.End = first, last
+					m.Text = text[loc[0] : loc[1]+sep[1]+endLoc[1]]
+					return m, true
+				}
+			}
+		}
+	}
+
+	m.Start, ok = resolve(start, now)
+	return m, ok
+}
+
+// matchDayOnly reads a range such as "5–7 Jul 2025" or "Fri 10 - Sun 12
+// October", whose start is a day of the month the end gives.
+func matchDayOnly(text string, loc []int, now time.Time) (Match, bool) {
+	g := groups(text, loc)
+	rest := text[loc[1]:]
+	endLoc := anchored[dayMonth].FindStringSubmatchIndex(rest)
+	if endLoc == nil {
+		return Match{}, false
+	}
+	end, ok := parse(dayMonth, groups(rest, endLoc))
+	if !ok {
+		return Match{}, false
+	}
+	start := date{month: end.month, day: atoi(g[2]), weekday: g[1]}
+	first, last, ok := resolveRange(start, end, now)
+	if !ok {
+		return Match{}, false
+	}
+	return Match{Start: first, End: last, Index: loc[0], Text: text[loc[0] : loc[1]+endLoc[1]]}, true
+}
+
+// groups returns the text of each group of a match, "" for those that did
+// not take part.
+func groups(text string, loc []int) []string {
+	g := make([]string, len(loc)/2)
+	for i := range g {
+		if loc[2*i] >= 0 {
+			g[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return g
+}
+
+// parse reads the groups of a date found by the pattern for kind.
+func parse(kind int, g []string) (date, bool) {
+	var d date
+	switch kind {
+	case dayMonth:
+		d.weekday, d.day, d.year = g[1], atoi(g[2]), atoi(g[4])
+		d.month, _ = parseMonth(g[3])
+	case monthDay:
+		d.weekday, d.day, d.year = g[1], atoi(g[3]), atoi(g[4])
+		d.month, _ = parseMonth(g[2])
+	case isoDate:
+		d.year, d.month, d.day = atoi(g[1]), time.Month(atoi(g[2])), atoi(g[3])
+	case numericDate:
+		d.day, d.month, d.year = atoi(g[1]), time.Month(atoi(g[2])), atoi(g[3])
+		if len(g[3]) == 2 {
+			d.year += 2000
+		}
+	}
+	if d.month < time.January || d.month > time.December || d.day < 1 || d.day > 31 {
+		return date{}, false
+	}
+	return d, true
+}
+
+// resolve places d in its year, inferring it from now if the text did not
+// give it, and rejects impossible days such as 31 June.
+func resolve(d date, now time.Time) (time.Time, bool) {
+	if d.year == 0 {
+		d.year = inferYear(d.month, d.day, d.weekday, now)
+	}
+	return at(d)
+}
+
+// resolveRange places a range in its years, taking a missing year from the
+// other end, or inferring it from now if neither gives one. The end must
+// follow the start by at most MaxRangeDays.
+func resolveRange(start, end date, now time.Time) (first, last time.Time, ok bool) {
+	switch {
+	case start.year == 0 && end.year == 0:
+		start.year = inferYear(start.month, start.day, start.weekday, now)
+		end.year = start.year
+		if end.month < start.month {
+			end.year++
+		}
+	case end.year == 0:
+		end.year = start.year
+		if end.month < start.month {
+			end.year++
+		}
+	case start.year == 0:
+		start.year = end.year
+		if start.month > end.month {
+			start.year--
+		}
+	}
+
+	first, ok = at(start)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	last, ok = at(end)
+	if !ok || !last.After(first) || last.Sub(first) > MaxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, false
+	}
+	return first, last, true
+}
+
+// at returns midnight UTC on d, if it exists.
+func at(d date) (time.Time, bool) {
+	t := time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC)
+	// Reject impossible days, which time.Date would normalise
+	if t.Day() != d.day || t.Month() != d.month {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Parse returns the first date in text, formatted with Layout. If it starts
+// a range such as "5–7 Jul 2025", endDate is the range's last day; otherwise
+// it is "". Both are "" if text has no date.
+func Parse(text string, now time.Time) (date, endDate string) {
+	m, ok := Find(text, now)
+	if !ok {
+		return "", ""
+	}
+	return Format(m.Start), Format(m.End)
+}
+
+// Format formats t with Layout, or returns "" for the zero time.
+func Format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(Layout)
+}
+
+// parseMonth reads a month name such as "Jul", "Sept" or "July", in any
+// case.
+func parseMonth(name string) (time.Month, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), name[:3]) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// parseWeekday reads a weekday name such as "Sat", "Thurs" or "Saturday", in
+// any case.
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.HasPrefix(strings.ToLower(d.String()), name[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// pastGrace is how long ago an undated day may be and still be taken as this
+// year's, such as the day of an event that has just finished.
+const pastGrace = 7 * 24 * time.Hour
+
+// inferYear picks a year, within one of now's, for month and day. Years are
+// ranked first by whether the date falls on weekday, if it names one, then by
+// whether the date is upcoming (no more than pastGrace before now), and then
+// by nearness: the soonest upcoming, or else the latest past. A weekday match
+// can therefore win with a past year.
+func inferYear(month time.Month, day int, weekday string, now time.Time) int {
+	want, hasWeekday := parseWeekday(weekday)
+
+	// Rank each year: matching the weekday first, then upcoming before past,
+	// then the soonest upcoming or the latest past
+	best, found := now.Year(), false
+	var bestMatches, bestUpcoming bool
+	var bestDate time.Time
+	for year := now.Year() - 1; year <= now.Year()+1; year++ {
+		date := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		// 29 February only exists in leap years
+		if date.Day() != day {
+			continue
+		}
+		matches := hasWeekday && date.Weekday() == want
+		upcoming := !date.Before(now.Add(-pastGrace))
+
+		var better bool
+		switch {
+		case !found:
+			better = true
+		case matches != bestMatches:
+			better = matches
+		case upcoming != bestUpcoming:
+			better = upcoming
+		case upcoming:
+			better = date.Before(bestDate)
+		default:
+			better = date.After(bestDate)
+		}
+		if better {
+			best, found = year, true
+			bestMatches, bestUpcoming, bestDate = matches, upcoming, date
+		}
+	}
+	return best
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+var (
+	// timeRangePattern matches "7:30 - 9am", whose start takes the end's am
+	// or pm if it has none. The groups are the start hour, minute and am or
+	// pm, then the end's am or pm.
+	timeRangePattern    = regexp.MustCompile(`(?i)\b(\d{1,2})(?:[:.](\d{2}))?\s*(?:([ap])\.?m\.?)?\s*(?:[-–—]|\bto\b)\s*\d{1,2}(?:[:.]\d{2})?\s*([ap])\.?m\.?\b`)
+	meridiemTimePattern = regexp.MustCompile(`(?i)\b(\d{1,2})(?:[:.](\d{2}))?\s*([ap])\.?m\.?\b`)
+	clockTimePattern    = regexp.MustCompile(`\b([01]?\d|2[0-3]):([0-5]\d)\b`)
+	noonPattern         = regexp.MustCompile(`(?i)\b(?:noon|midday)\b`)
+)
+
+// ParseTime finds a time of day such as "7:30am", "6pm", "19:00" or "noon"
+// and returns it as "15:04", or "" if there is none. Of a range such as
+// "7:30 - 9am" it returns the start.
+func ParseTime(text string) string {
+	if m := timeRangePattern.FindStringSubmatch(text); m != nil {
+		meridiem := m[3]
+		if meridiem == "" {
+			meridiem = m[4]
+		}
+		if t := clock12(m[1], m[2], meridiem); t != "" {
+			return t
+		}
+	}
+
+	if m := meridiemTimePattern.FindStringSubmatch(text); m != nil {
+		return clock12(m[1], m[2], m[3])
+	}
+
+	if m := clockTimePattern.FindStringSubmatch(text); m != nil {
+		return fmt.Sprintf("%02d:%02d", atoi(m[1]), atoi(m[2]))
+	}
+
+	if noonPattern.MatchString(text) {
+		return "12:00"
+	}
+
+	return ""
+}
+
+// clock12 converts a 12-hour time to "15:04", or "" if it is not one.
+func clock12(hour, minute, meridiem string) string {
+	h, min := atoi(hour), atoi(minute)
+	if h < 1 || h > 12 || min > 59 {
+		return ""
+	}
+	if h == 12 {
+		h = 0
+	}
+	if strings.EqualFold(meridiem, "p") {
+		h += 12
+	}
+	return fmt.Sprintf("%02d:%02d", h, min)
+}
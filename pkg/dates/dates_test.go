@@ -0,0 +1,267 @@
+package dates
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// scrapedAt is when the test pages were read, for inferring missing years.
+var scrapedAt = time.Date(2025, 6, 20, 9, 0, 0, 0, time.UTC)
+
+func TestDateParsing(t *testing.T) {
+	testCases := []string{
+		"Sat, 5 Jul 2025",
+		"5 Jul 2025",
+		"2025-07-05",
+		"Sunday, 13 July 2025",
+		"Sat 5th July",
+		"Sun 13 Jul",
+		"05/07/2025",
+		"5–7 Jul 2025",
+		"July 5th, 2025",
+	}
+
+	for _, tc := range testCases {
+		if date, _ := Parse(tc, scrapedAt); date == "" {
+			t.Errorf("Parse(%q) returned empty string", tc)
+		}
+	}
+}
+
+func TestEnhancedDateParsing(t *testing.T) {
+	testCases := []struct {
+		input       string
+		shouldParse bool
+	}{
+		{"Sat, 5 Jul 2025", true},
+		{"Sunday, 13 July 2025", true},
+		{"5 Jul 2025", true},
+		{"2025-07-05", true},
+		{"Sun 6 Jul 2025", true},
+		{"Saturday the 5th of July", true},
+		{"Thurs 3rd Jul", true},
+		{"5 Sept 2025", true},
+		{"5.7.25", true},
+		{"Racing from 7:30am", false},
+		{"Race 13 of 20", false},
+		{"A Grade 5 laps", false},
+		{"31/02/2025", false},
+		{"13/13/2025", false},
+		{"31 Jun 2025", false},
+		{"v1.2.3", false},
+		{"05/07/2025b", false},
+		{"invalid date", false},
+		{"", false},
+		{"just some text", false},
+	}
+
+	for _, tc := range testCases {
+		date, _ := Parse(tc.input, scrapedAt)
+		if tc.shouldParse && date == "" {
+			t.Errorf("Parse(%q) should have parsed a date but returned empty", tc.input)
+		}
+		if !tc.shouldParse && date != "" {
+			t.Errorf("Parse(%q) should not have parsed but returned %q", tc.input, date)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input         string
+		date, endDate string
+	}{
+		// Day, month and year
+		{"Sat, 5 Jul 2025", "2025-07-05T00:00:00Z", ""},
+		{"Sunday, 13 July 2025", "2025-07-13T00:00:00Z", ""},
+		{"13 Jan 2025", "2025-01-13T00:00:00Z", ""},
+		{"5 July 2025", "2025-07-05T00:00:00Z", ""},
+		{"Entries close Thu, 3 Jul 2025 11:59pm", "2025-07-03T00:00:00Z", ""},
+		{"Sat, 5 Jul 1500m", "2025-07-05T00:00:00Z", ""},
+
+		// Ordinals
+		{"Sat 5th July 2025", "2025-07-05T00:00:00Z", ""},
+		{"Tuesday the 1st of July", "2025-07-01T00:00:00Z", ""},
+		{"22nd Jun", "2025-06-22T00:00:00Z", ""},
+		{"3rd Aug.", "2025-08-03T00:00:00Z", ""},
+
+		// Month first
+		{"December 25 2025", "2025-12-25T00:00:00Z", ""},
+		{"Mar 15 2025", "2025-03-15T00:00:00Z", ""},
+		{"July 5th, 2025", "2025-07-05T00:00:00Z", ""},
+		{"Saturday, July 5", "2025-07-05T00:00:00Z", ""},
+
+		// Numeric, day first as in Australia
+		{"2025-07-05", "2025-07-05T00:00:00Z", ""},
+		{"05/07/2025", "2025-07-05T00:00:00Z", ""},
+		{"5/7/25", "2025-07-05T00:00:00Z", ""},
+		{"Date: 12.10.2025", "2025-10-12T00:00:00Z", ""},
+
+		// Ranges
+		{"5–7 Jul 2025", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+		{"5th - 7th July", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+		{"Sat 5 – Sun 6 Jul 2025", "2025-07-05T00:00:00Z", "2025-07-06T00:00:00Z"},
+		{"30 Jun - 2 Jul 2025", "2025-06-30T00:00:00Z", "2025-07-02T00:00:00Z"},
+		{"Tue 30 Dec 2025 to Thu 1 Jan 2026", "2025-12-30T00:00:00Z", "2026-01-01T00:00:00Z"},
+		{"Tour of the Hills, Friday 10 - Sunday 12 October 2025", "2025-10-10T00:00:00Z", "2025-10-12T00:00:00Z"},
+		{"July 5–7, 2025", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+		{"05/07/2025 - 06/07/2025", "2025-07-05T00:00:00Z", "2025-07-06T00:00:00Z"},
+		{"2025-07-05 until 2025-07-07", "2025-07-05T00:00:00Z", "2025-07-07T00:00:00Z"},
+
+		// Not ranges
+		{"Sat, 5 Jul 2025 7:30 - 9 am", "2025-07-05T00:00:00Z", ""},
+		{"Sat, 5 Jul 2025, then 12–14 Sep 2025", "2025-07-05T00:00:00Z", ""},
+		{"7–5 Jul 2025", "2025-07-05T00:00:00Z", ""},
+		{"31 Jun - 2 Jul 2025", "2025-07-02T00:00:00Z", ""},
+		{"1 Jan 2025 - 5 Mar 2025", "2025-01-01T00:00:00Z", ""},
+
+		{"no date here", "", ""},
+	}
+
+	for _, tc := range testCases {
+		date, endDate := Parse(tc.input, scrapedAt)
+		if date != tc.date || endDate != tc.endDate {
+			t.Errorf("Parse(%q) = (%q, %q), want (%q, %q)", tc.input, date, endDate, tc.date, tc.endDate)
+		}
+	}
+}
+
+func TestParseInfersYear(t *testing.T) {
+	testCases := []struct {
+		input         string
+		now           time.Time
+		date, endDate string
+	}{
+		// The next 13 July after the scrape
+		{"Sun 13 Jul", scrapedAt, "2025-07-13T00:00:00Z", ""},
+		{"13 Jul", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "2026-07-13T00:00:00Z", ""},
+		// A date already past this year is next year's
+		{"10 Jan", time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC), "2026-01-10T00:00:00Z", ""},
+		{"20 Dec", time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), "2026-12-20T00:00:00Z", ""},
+		{"5 Jan", time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC), "2026-01-05T00:00:00Z", ""},
+		// Unless it has only just passed
+		{"31 Dec", time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), "2025-12-31T00:00:00Z", ""},
+		{"18 Jun", scrapedAt, "2025-06-18T00:00:00Z", ""},
+		// 5 July is a Saturday in 2025 but not in 2026 or 2027
+		{"Sat 5 – Sun 6 Jul", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "2025-07-05T00:00:00Z", "2025-07-06T00:00:00Z"},
+		{"Sat 5th July", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "2025-07-05T00:00:00Z", ""},
+		// Without a weekday the next occurrence wins
+		{"5–7 Jul", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "2026-07-05T00:00:00Z", "2026-07-07T00:00:00Z"},
+		{"30 Dec – 1 Jan", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "2026-12-30T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{"30 Dec – 1 Jan", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "2025-12-30T00:00:00Z", "2026-01-01T00:00:00Z"},
+		// 29 February falls in the next leap year
+		{"29 Feb", time.Date(2027, 11, 1, 0, 0, 0, 0, time.UTC), "2028-02-29T00:00:00Z", ""},
+	}
+
+	for _, tc := range testCases {
+		date, endDate := Parse(tc.input, tc.now)
+		if date != tc.date || endDate != tc.endDate {
+			t.Errorf("Parse(%q) at %s = (%q, %q), want (%q, %q)", tc.input, tc.now.Format("2006-01-02"), date, endDate, tc.date, tc.endDate)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	text := "Round 1: Sat 5th Jul. Round 2: 12–13 Jul. Round 3: 26/07/2025 (7:30am)"
+	want := []struct {
+		text          string
+		date, endDate string
+	}{
+		{"Sat 5th Jul.", "2025-07-05T00:00:00Z", ""},
+		{"12–13 Jul.", "2025-07-12T00:00:00Z", "2025-07-13T00:00:00Z"},
+		{"26/07/2025", "2025-07-26T00:00:00Z", ""},
+	}
+
+	matches := FindAll(text, scrapedAt, -1)
+	if len(matches) != len(want) {
+		t.Fatalf("FindAll found %d dates, want %d: %+v", len(matches), len(want), matches)
+	}
+	for i, m := range matches {
+		if m.Text != want[i].text || Format(m.Start) != want[i].date || Format(m.End) != want[i].endDate {
+			t.Errorf("match %d = %q (%s, %s), want %q (%s, %s)", i, m.Text, Format(m.Start), Format(m.End), want[i].text, want[i].date, want[i].endDate)
+		}
+		if text[m.Index:m.Index+len(m.Text)] != m.Text {
+			t.Errorf("match %d at %d is not %q", i, m.Index, m.Text)
+		}
+	}
+
+	if matches := FindAll(text, scrapedAt, 2); len(matches) != 2 {
+		t.Errorf("FindAll(n=2) found %d dates, want 2", len(matches))
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"8:30am", "08:30"},
+		{"7:30am", "07:30"},
+		{"Racing from 6pm", "18:00"},
+		{"12:15 pm", "12:15"},
+		{"12am", "00:00"},
+		{"19:05", "19:05"},
+		{"7.45 a.m.", "07:45"},
+		{"7:30 - 9 am", "07:30"},
+		{"6 to 8pm", "18:00"},
+		{"11am–2pm", "11:00"},
+		{"Sat, 5 Jul 2025 11:59pm", "23:59"},
+		{"Start at noon", "12:00"},
+		{"no time here", ""},
+		{"Race 13", ""},
+		{"13pm", ""},
+		{"24:00", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := ParseTime(tc.input); got != tc.expected {
+			t.Errorf("ParseTime(%q) = %q, want %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func FuzzFindAll(f *testing.F) {
+	for _, seed := range []string{
+		"Sat, 5 Jul 2025",
+		"Sat 5th – Sun 6th July",
+		"05/07/2025 - 06/07/2025",
+		"July 5–7, 2025",
+		"31 Jun - 2 Jul 2025",
+		"2025-07-05T08:00:00+10:00",
+		"Round 1: 29 Feb. Round 2: 1/3/24",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		next := 0
+		for _, m := range FindAll(text, scrapedAt, -1) {
+			if m.Index < next || m.Index+len(m.Text) > len(text) || text[m.Index:m.Index+len(m.Text)] != m.Text {
+				t.Fatalf("FindAll(%q) gave %+v, not in the text after %d", text, m, next)
+			}
+			next = m.Index + len(m.Text)
+
+			if m.Start.IsZero() || m.Start.Location() != time.UTC || m.Start.Hour() != 0 || m.Start.Minute() != 0 {
+				t.Fatalf("FindAll(%q) started at %v, want midnight UTC", text, m.Start)
+			}
+			if !m.End.IsZero() && (!m.End.After(m.Start) || m.End.Sub(m.Start) > MaxRangeDays*24*time.Hour) {
+				t.Fatalf("FindAll(%q) gave range %v to %v", text, m.Start, m.End)
+			}
+		}
+	})
+}
+
+var clock = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+func FuzzParseTime(f *testing.F) {
+	for _, seed := range []string{"7:30am", "7.45 a.m.", "19:05", "7:30 - 9 am", "noon", "12am"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if got := ParseTime(text); got != "" && !clock.MatchString(got) {
+			t.Fatalf("ParseTime(%q) = %q, not a time of day", text, got)
+		}
+	})
+}
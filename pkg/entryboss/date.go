@@ -0,0 +1,198 @@
+package entryboss
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/dates"
+)
+
+// maxDateLevels is how many ancestors of a race link are searched for its
+// date.
+const maxDateLevels = 5
+
+// lowDateConfidence is the confidence below which a date is reported for
+// review.
+const lowDateConfidence = 0.5
+
+// foundDate is the date chosen for a race link.
+type foundDate struct {
+	date, endDate string
+	confidence    float64 // from 0 to 1
+	method        string  // where it was found: "link", "ancestor", "sibling" or "row"
+}
+
+// dateCandidate is a date found near a race link.
+type dateCandidate struct {
+	date, endDate string
+	distance      int // DOM steps from the link
+	method        string
+
+	// shared is set for a date in an element that holds links to other
+	// races too, so may be theirs rather than this one's
+	shared bool
+}
+
+// extractEventDate finds the date of the event behind a link. Dates are
+// gathered from the link's text, its ancestors and the elements beside them,
+// each with its distance from the link. The closest distance at which the
+// dates found agree wins, and its confidence falls with distance, with dates
+// shared with other races and with closer dates that disagreed. An ancestor
+// holding several dates, such as a list of events, is not searched further
+// up, so a date in a page header is not given to every race below it.
+func extractEventDate(eventLink *goquery.Selection, now time.Time) (foundDate, bool) {
+	href, _ := eventLink.Attr("href")
+	var candidates []dateCandidate
+	add := func(text string, distance int, method string, shared bool) (dates int) {
+		found := datesIn(text, now)
+		if len(found) == 1 {
+			candidates = append(candidates, dateCandidate{found[0][0], found[0][1], distance, method, shared})
+		}
+		return len(found)
+	}
+
+	add(spacedText(eventLink), 0, "link", false)
+	add(spacedText(eventLink.Prev()), 1, "sibling", false)
+	add(spacedText(eventLink.Next()), 1, "sibling", false)
+
+	parent := eventLink.Parent()
+	for level := 1; level <= maxDateLevels && parent.Length() > 0; level++ {
+		shared := hasOtherRaces(parent, href)
+		if add(spacedText(parent), level, "ancestor", shared) > 1 {
+			break
+		}
+		// A date beside an ancestor is shared by every race inside it
+		add(spacedText(parent.Prev()), level+1, "sibling", shared)
+		add(spacedText(parent.Next()), level+1, "sibling", shared)
+		parent = parent.Parent()
+	}
+
+	return pickDate(candidates)
+}
+
+// pickDate chooses among candidates the closest date no other candidate at
+// the same distance disagrees with.
+func pickDate(candidates []dateCandidate) (foundDate, bool) {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	ambiguous := false
+	for i := 0; i < len(candidates); {
+		j := i
+		agree := true
+		for j < len(candidates) && candidates[j].distance == candidates[i].distance {
+			agree = agree && candidates[j].date == candidates[i].date && candidates[j].endDate == candidates[i].endDate
+			j++
+		}
+		if !agree {
+			ambiguous = true
+			i = j
+			continue
+		}
+
+		// The most telling of the agreeing candidates scores the date
+		var best foundDate
+		for _, c := range candidates[i:j] {
+			if confidence := dateConfidence(c, ambiguous); confidence > best.confidence {
+				best = foundDate{c.date, c.endDate, confidence, c.method}
+			}
+		}
+		return best, true
+	}
+	return foundDate{}, false
+}
+
+// dateConfidence scores a candidate: a date in the link itself is certain,
+// and one in its parent or beside it nearly so, less the further away it
+// was found, when other races share it, and when closer candidates disagreed.
+func dateConfidence(c dateCandidate, ambiguous bool) float64 {
+	confidence := 1.0
+	if c.distance > 0 {
+		confidence = 0.9 - 0.15*float64(c.distance-1)
+	}
+	if c.method == "sibling" {
+		confidence -= 0.1
+	}
+	if c.shared {
+		confidence *= 0.6
+	}
+	if ambiguous {
+		confidence *= 0.5
+	}
+	return math.Round(math.Max(confidence, 0.1)*100) / 100
+}
+
+// hasOtherRaces reports whether sel holds links to races other than href.
+func hasOtherRaces(sel *goquery.Selection, href string) bool {
+	other := false
+	sel.Find("a[href*='/races/']").EachWithBreak(func(i int, link *goquery.Selection) bool {
+		h, _ := link.Attr("href")
+		other = h != href
+		return !other
+	})
+	return other
+}
+
+// inlineElements are the elements whose text runs on from the text around
+// them when shown, unlike table cells, list items and other blocks.
+var inlineElements = map[string]bool{
+	"a": true, "abbr": true, "b": true, "em": true, "i": true, "small": true,
+	"span": true, "strong": true, "sub": true, "sup": true, "time": true,
+}
+
+// spacedText returns the text of sel with a space around each block element,
+// so that neighbouring cells such as "5 Jul 2025" and "Winter Criterium" do
+// not run together as they do in Text.
+func spacedText(sel *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(*goquery.Selection)
+	walk = func(sel *goquery.Selection) {
+		sel.Contents().Each(func(i int, node *goquery.Selection) {
+			name := goquery.NodeName(node)
+			switch {
+			case name == "#text":
+				b.WriteString(node.Text())
+			case inlineElements[name]:
+				walk(node)
+			default:
+				b.WriteString(" ")
+				walk(node)
+				b.WriteString(" ")
+			}
+		})
+	}
+	sel.Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "#text" {
+			b.WriteString(s.Text())
+			return
+		}
+		walk(s)
+	})
+	return b.String()
+}
+
+// maxDatesIn bounds the dates datesIn looks for; two are enough to tell
+// that text is ambiguous.
+const maxDatesIn = 2
+
+// datesIn returns the different dates in text, up to maxDatesIn, each as
+// its date and end date. now is when the page was read, by which missing
+// years are inferred.
+func datesIn(text string, now time.Time) [][2]string {
+	var found [][2]string
+	seen := make(map[[2]string]bool)
+	for _, m := range dates.FindAll(text, now, -1) {
+		d := [2]string{dates.Format(m.Start), dates.Format(m.End)}
+		if !seen[d] {
+			seen[d] = true
+			found = append(found, d)
+		}
+		if len(found) == maxDatesIn {
+			break
+		}
+	}
+	return found
+}
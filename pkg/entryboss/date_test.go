@@ -0,0 +1,91 @@
+package entryboss
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractEventDate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		html       string
+		date       string
+		method     string
+		confidence float64
+	}{
+		{
+			name:       "in link",
+			html:       `<a href="/races/1">Crit Sat, 5 Jul 2025</a>`,
+			date:       "2025-07-05T00:00:00Z",
+			method:     "link",
+			confidence: 1,
+		},
+		{
+			name:       "beside link",
+			html:       `<div><span>Sat, 5 Jul 2025</span><a href="/races/1">Crit</a></div>`,
+			date:       "2025-07-05T00:00:00Z",
+			method:     "ancestor",
+			confidence: 0.9,
+		},
+		{
+			name:       "without year",
+			html:       `<div><span>Sat 5th July</span> <a href="/races/1">Crit</a></div>`,
+			date:       "2025-07-05T00:00:00Z",
+			method:     "ancestor",
+			confidence: 0.9,
+		},
+		{
+			name:       "beside parent",
+			html:       `<ul><li><span>Sat, 5 Jul 2025</span></li><li><a href="/races/1">Crit</a></li></ul>`,
+			date:       "2025-07-05T00:00:00Z",
+			method:     "ancestor",
+			confidence: 0.75,
+		},
+		{
+			name:       "closest of several",
+			html:       `<div><h2>Sun, 6 Jul 2025</h2><p>Sat, 5 Jul 2025 <a href="/races/1">Crit</a></p><p>Sun, 6 Jul 2025 <a href="/races/2">Road Race</a></p></div>`,
+			date:       "2025-07-05T00:00:00Z",
+			method:     "ancestor",
+			confidence: 0.9,
+		},
+		{
+			name:       "page header shared by every race",
+			html:       `<div><h1>Updated Sat, 5 Jul 2025</h1><ul><li><a href="/races/1">Race A</a></li><li><a href="/races/2">Race B</a></li></ul></div>`,
+			date:       "2025-07-05T00:00:00Z",
+			method:     "ancestor",
+			confidence: 0.36,
+		},
+		{
+			name: "several dates",
+			html: `<p>Sat, 5 Jul 2025 <a href="/races/1">Race A</a> Sun, 6 Jul 2025 <a href="/races/2">Race B</a></p>`,
+		},
+	}
+
+	now := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+
+			found, ok := extractEventDate(doc.Find(`a[href="/races/1"]`), now)
+			if tc.date == "" {
+				if ok {
+					t.Errorf("extractEventDate found %+v, want none", found)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("extractEventDate found no date, want %s", tc.date)
+			}
+			if found.date != tc.date || found.method != tc.method || found.confidence != tc.confidence {
+				t.Errorf("extractEventDate = %s by %s (%.2f), want %s by %s (%.2f)",
+					found.date, found.method, found.confidence, tc.date, tc.method, tc.confidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package entryboss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/dates"
+)
+
+// RaceDetails is what a race page says about an event. It is more reliable
+// than the date guessed from text near a link on the club calendar.
+type RaceDetails struct {
+	Date         string   `json:"date,omitempty"`      // same layout as Event.EventDate
+	EndDate      string   `json:"endDate,omitempty"`   // last day of a multi-day event
+	StartTime    string   `json:"startTime,omitempty"` // local time as "15:04"
+	Venue        string   `json:"venue,omitempty"`
+	EntriesClose string   `json:"entriesClose,omitempty"`
+	Grades       []string `json:"grades,omitempty"`
+	Status       string   `json:"status,omitempty"`
+}
+
+// apply copies every detail that was found onto the event.
+func (d RaceDetails) apply(event *calendar.Event) {
+	if d.Date != "" {
+		event.EventDate = d.Date
+		// The race page's date replaces the listing's, range and all, and
+		// leaves no doubt about which race it belongs to
+		event.EndDate = d.EndDate
+		event.DateConfidence, event.DateMethod = 1, "race page"
+	}
+	if d.StartTime != "" {
+		event.StartTime = d.StartTime
+	}
+	if d.Venue != "" {
+		event.Venue = d.Venue
+	}
+	if d.EntriesClose != "" {
+		event.EntriesClose = d.EntriesClose
+	}
+	if len(d.Grades) > 0 {
+		event.Grades = d.Grades
+	}
+	if d.Status != "" {
+		event.Status = d.Status
+	}
+}
+
+// addDetails fetches the race page of each event and merges its details in.
+// Events whose page cannot be fetched keep the data from the club calendar.
+func (s *Source) addDetails(ctx context.Context, events []calendar.Event) {
+	for i := range events {
+		details, err := s.raceDetails(ctx, events[i])
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			calendar.Logf(s.Log, "Failed to fetch race details for %s: %v\n", events[i].EventName, err)
+			continue
+		}
+		details.apply(&events[i])
+	}
+}
+
+// raceDetails returns the details of an event's race page, from the cache
+// when the club calendar still lists the race unchanged.
+func (s *Source) raceDetails(ctx context.Context, event calendar.Event) (RaceDetails, error) {
+	now := time.Now()
+	listing := event.EventName + "|" + event.EventDate
+
+	entry := s.DetailsCache.get(event.EventURL)
+	if entry != nil && entry.Listing == listing && now.Sub(entry.FetchedAt) < s.DetailsCache.MaxAge {
+		return entry.Details, nil
+	}
+
+	header := make(http.Header)
+	if entry != nil {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	p, err := s.fetchPage(ctx, event.EventURL, header)
+	if err != nil {
+		return RaceDetails{}, err
+	}
+
+	var details RaceDetails
+	switch {
+	case p.doc != nil:
+		details = parseRaceDetails(p.doc, now)
+	case entry != nil:
+		details = entry.Details
+	default:
+		return RaceDetails{}, errors.New("unexpected 304 Not Modified")
+	}
+
+	s.DetailsCache.put(event.EventURL, &detailsEntry{
+		FetchedAt:    now,
+		Listing:      listing,
+		EventDate:    event.EventDate,
+		State:        event.State,
+		ETag:         p.header.Get("ETag"),
+		LastModified: p.header.Get("Last-Modified"),
+		Details:      details,
+	})
+	return details, nil
+}
+
+// raceFieldLabels maps the labels used on race pages to RaceDetails fields.
+var raceFieldLabels = map[string]string{
+	"date":              "date",
+	"race date":         "date",
+	"event date":        "date",
+	"when":              "date",
+	"start":             "start",
+	"start time":        "start",
+	"time":              "start",
+	"first race":        "start",
+	"venue":             "venue",
+	"location":          "venue",
+	"where":             "venue",
+	"course":            "venue",
+	"entries close":     "entriesClose",
+	"entries closing":   "entriesClose",
+	"entry deadline":    "entriesClose",
+	"entries close at":  "entriesClose",
+	"registration ends": "entriesClose",
+	"grades":            "grades",
+	"grade":             "grades",
+	"categories":        "grades",
+	"status":            "status",
+}
+
+var gradeSeparators = regexp.MustCompile(`\s*(?:,|;|\||\n|/)\s*`)
+
+// parseRaceDetails reads a race page. Structured data (schema.org JSON-LD) is
+// preferred; labelled fields in definition lists, tables and "Label: value"
+// lines fill any gaps. Dates without a year are placed as dates.Parse does.
+func parseRaceDetails(doc *goquery.Document, now time.Time) RaceDetails {
+	var d RaceDetails
+	parseJSONLD(doc, &d, now)
+
+	fields := labelledFields(doc)
+	labels := make([]string, 0, len(fields))
+	for label := range fields {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		value := fields[label]
+		switch raceFieldLabels[label] {
+		case "date":
+			if d.Date == "" {
+				d.Date, d.EndDate = dates.Parse(value, now)
+			}
+			if d.StartTime == "" {
+				d.StartTime = dates.ParseTime(value)
+			}
+		case "start":
+			if d.StartTime == "" {
+				d.StartTime = dates.ParseTime(value)
+			}
+		case "venue":
+			if d.Venue == "" {
+				d.Venue = value
+			}
+		case "entriesClose":
+			if d.EntriesClose == "" {
+				d.EntriesClose, _ = dates.Parse(value, now)
+			}
+		case "grades":
+			if len(d.Grades) == 0 {
+				for _, grade := range gradeSeparators.Split(value, -1) {
+					if grade != "" {
+						d.Grades = append(d.Grades, grade)
+					}
+				}
+			}
+		case "status":
+			if d.Status == "" {
+				if d.Status = normaliseStatus(value); d.Status == "" {
+					d.Status = value
+				}
+			}
+		}
+	}
+
+	// Fall back to status badges, or a cancellation announced in a heading
+	if d.Status == "" {
+		doc.Find(".alert, .badge, .label, .status").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			d.Status = normaliseStatus(s.Text())
+			return d.Status == ""
+		})
+	}
+	if d.Status == "" {
+		doc.Find("h1, h2").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			if status := normaliseStatus(s.Text()); status == "Cancelled" || status == "Postponed" {
+				d.Status = status
+			}
+			return d.Status == ""
+		})
+	}
+
+	return d
+}
+
+// labelledFields collects label/value pairs from the page, keyed by the
+// lower-cased label without a trailing colon. The first value for a label wins.
+func labelledFields(doc *goquery.Document) map[string]string {
+	fields := make(map[string]string)
+	add := func(label, value string) {
+		label = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(label), ":")))
+		value = strings.Join(strings.Fields(value), " ")
+		if label == "" || value == "" {
+			return
+		}
+		if _, exists := fields[label]; !exists {
+			fields[label] = value
+		}
+	}
+
+	doc.Find("dt").Each(func(i int, dt *goquery.Selection) {
+		add(dt.Text(), dt.NextFiltered("dd").Text())
+	})
+
+	doc.Find("tr").Each(func(i int, tr *goquery.Selection) {
+		cells := tr.Children()
+		if cells.Length() == 2 {
+			add(cells.First().Text(), cells.Last().Text())
+		}
+	})
+
+	doc.Find("p, li, div, span").Each(func(i int, el *goquery.Selection) {
+		// Only leaf-like elements, so a label is not paired with a whole section
+		if el.Children().Length() > 2 {
+			return
+		}
+		label, value, found := strings.Cut(el.Text(), ":")
+		if found && len(strings.TrimSpace(label)) <= 30 {
+			if _, known := raceFieldLabels[strings.ToLower(strings.TrimSpace(label))]; known {
+				add(label, value)
+			}
+		}
+	})
+
+	return fields
+}
+
+// parseJSONLD fills details from schema.org Event data embedded in the page.
+func parseJSONLD(doc *goquery.Document, d *RaceDetails, now time.Time) {
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, script *goquery.Selection) {
+		var raw interface{}
+		if err := json.Unmarshal([]byte(script.Text()), &raw); err != nil {
+			return
+		}
+
+		var items []interface{}
+		switch v := raw.(type) {
+		case []interface{}:
+			items = v
+		default:
+			items = []interface{}{v}
+		}
+
+		for _, item := range items {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			start, _ := obj["startDate"].(string)
+			if start == "" {
+				continue
+			}
+
+			if t, err := time.Parse(time.RFC3339, start); err == nil {
+				// Keep the organiser's wall-clock date and time
+				d.Date = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Format("2006-01-02T15:04:05Z")
+				// Midnight is how date-only events are often written, not a start time
+				if t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 {
+					d.StartTime = t.Format("15:04")
+				}
+			} else {
+				d.Date, _ = dates.Parse(start, now)
+			}
+
+			if end, _ := obj["endDate"].(string); end != "" {
+				endDate, _ := dates.Parse(end, now) // wall-clock date, as for startDate
+				if endDate > d.Date {
+					d.EndDate = endDate
+				}
+			}
+
+			switch location := obj["location"].(type) {
+			case string:
+				d.Venue = location
+			case map[string]interface{}:
+				d.Venue, _ = location["name"].(string)
+			}
+
+			if status, ok := obj["eventStatus"].(string); ok {
+				d.Status = normaliseStatus(strings.TrimPrefix(status, "https://schema.org/Event"))
+			}
+			return
+		}
+	})
+}
+
+var statusPatterns = []struct {
+	pattern *regexp.Regexp
+	status  string
+}{
+	{regexp.MustCompile(`\bcancell?ed\b|\bcanceled\b`), "Cancelled"},
+	{regexp.MustCompile(`\bpostponed\b`), "Postponed"},
+	{regexp.MustCompile(`\brescheduled\b`), "Rescheduled"},
+	{regexp.MustCompile(`\bsold out\b|\bfully booked\b|\b(?:event|race|entries|entry list|field|registrations?) (?:(?:is|are|now) )*full\b`), "Full"},
+	{regexp.MustCompile(`\b(?:entries|entry|registrations?) (?:(?:are|is|now|have) )*closed\b`), "Entries closed"},
+	{regexp.MustCompile(`\b(?:entries|entry|registrations?) (?:(?:are|is|now) )*open\b`), "Entries open"},
+}
+
+// normaliseStatus maps free-text status banners to a short canonical status,
+// or "" if the text does not describe one. Entry states need a phrase such as
+// "entries open" or "event full", so labels like "Open men" or "Full results"
+// are not taken for a status.
+func normaliseStatus(text string) string {
+	text = strings.ToLower(text)
+	for _, sp := range statusPatterns {
+		if sp.pattern.MatchString(text) {
+			return sp.status
+		}
+	}
+	return ""
+}
+
+// DetailsCache remembers race details between runs so unchanged races are not
+// refetched every day. It is safe for concurrent use.
+type DetailsCache struct {
+	// MaxAge is how long cached details are used without asking the server,
+	// provided the club calendar still lists the race with the same name and
+	// date. After that the page is requested conditionally.
+	MaxAge time.Duration
+
+	path    string
+	mu      sync.Mutex
+	entries map[string]*detailsEntry
+}
+
+type detailsEntry struct {
+	FetchedAt    time.Time   `json:"fetchedAt"`
+	Listing      string      `json:"listing"`
+	EventDate    string      `json:"eventDate"`
+	State        string      `json:"state,omitempty"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Details      RaceDetails `json:"details"`
+}
+
+// OpenDetailsCache loads the cache stored at path. A missing file gives an
+// empty cache.
+func OpenDetailsCache(path string, maxAge time.Duration) (*DetailsCache, error) {
+	c := &DetailsCache{MaxAge: maxAge, path: path, entries: make(map[string]*detailsEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read details cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse details cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cache back to its file, dropping races that are over in
+// their state's local time.
+func (c *DetailsCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for url, entry := range c.entries {
+		cutoff := calendar.Cutoff(entry.State, now)
+		last := entry.EventDate
+		if entry.Details.EndDate > last {
+			last = entry.Details.EndDate
+		}
+		if last < cutoff {
+			delete(c.entries, url)
+		}
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal details cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create details cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write details cache: %w", err)
+	}
+	return nil
+}
+
+func (c *DetailsCache) get(url string) *detailsEntry {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[url]
+}
+
+func (c *DetailsCache) put(url string, entry *detailsEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = entry
+}
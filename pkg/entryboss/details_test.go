@@ -0,0 +1,255 @@
+package entryboss
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/calendar"
+)
+
+const racePageHTML = `<html><body>
+<h1>Winter Criterium</h1>
+<div class="badge">Entries Open</div>
+<dl>
+  <dt>Date</dt><dd>Saturday, 5 July 2025</dd>
+  <dt>Start Time</dt><dd>8:30am</dd>
+  <dt>Venue</dt><dd>Darebin  International Sports Centre</dd>
+  <dt>Entries Close</dt><dd>Thu, 3 Jul 2025 11:59pm</dd>
+</dl>
+<table><tr><th>Grades</th><td>A Grade, B Grade, C Grade</td></tr></table>
+</body></html>`
+
+const jsonLDRacePageHTML = `<html><head>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@type": "SportsEvent", "name": "Spring Road Race",
+ "startDate": "2025-09-14T07:45:00+10:00", "location": {"@type": "Place", "name": "Kinglake"},
+ "eventStatus": "https://schema.org/EventCancelled"}
+</script></head><body><h1>Spring Road Race</h1></body></html>`
+
+const midnightRacePageHTML = `<html><head>
+<script type="application/ld+json">
+{"@type": "SportsEvent", "name": "Club Championships", "startDate": "2025-10-04T00:00:00+10:00"}
+</script></head><body><h1>Club Championships</h1><div class="badge">Full results</div></body></html>`
+
+func parseHTML(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func TestParseRaceDetails(t *testing.T) {
+	testCases := []struct {
+		name     string
+		html     string
+		expected RaceDetails
+	}{
+		{
+			name: "labelled fields",
+			html: racePageHTML,
+			expected: RaceDetails{
+				Date:         "2025-07-05T00:00:00Z",
+				StartTime:    "08:30",
+				Venue:        "Darebin International Sports Centre",
+				EntriesClose: "2025-07-03T00:00:00Z",
+				Grades:       []string{"A Grade", "B Grade", "C Grade"},
+				Status:       "Entries open",
+			},
+		},
+		{
+			name: "JSON-LD",
+			html: jsonLDRacePageHTML,
+			expected: RaceDetails{
+				Date:      "2025-09-14T00:00:00Z",
+				StartTime: "07:45",
+				Venue:     "Kinglake",
+				Status:    "Cancelled",
+			},
+		},
+		{
+			name: "JSON-LD without a start time",
+			html: midnightRacePageHTML,
+			expected: RaceDetails{
+				Date: "2025-10-04T00:00:00Z",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseRaceDetails(parseHTML(t, tc.html), time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC))
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("parseRaceDetails() = %+v, want %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNormaliseStatus(t *testing.T) {
+	testCases := []struct {
+		text, want string
+	}{
+		{"Entries Open", "Entries open"},
+		{"Registrations are now open", "Entries open"},
+		{"Entries closed", "Entries closed"},
+		{"SOLD OUT", "Full"},
+		{"Event full", "Full"},
+		{"Cancelled due to weather", "Cancelled"},
+		{"Open men", ""},
+		{"Full results", ""},
+		{"Road closed to traffic", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := normaliseStatus(tc.text); got != tc.want {
+			t.Errorf("normaliseStatus(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFetchEventsDropsRacesMovedIntoThePast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/calendar/club":
+			fmt.Fprint(w, `<table><tr><td>Sat, 5 Jul 2099</td><td><a href="/races/100">Winter Criterium</a></td></tr></table>`)
+		case "/races/100":
+			fmt.Fprint(w, `<dl><dt>Date</dt><dd>Saturday, 5 July 2025</dd></dl>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Limiter = nil
+	src.Details = true
+	clubs := []calendar.Club{{ClubName: "Club", ClubURL: server.URL + "/calendar/club", State: "VIC"}}
+
+	result, err := src.FetchEvents(context.Background(), "VIC", clubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 0 {
+		t.Errorf("Expected the race dated in the past by its page to be dropped, got %+v", result.Events)
+	}
+}
+
+func TestFetchEventsTakesDateFromRacePage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/calendar/club":
+			// Two dates in the row make its first one a poor guess
+			fmt.Fprint(w, `<table><tr><td>Sat, 5 Jul 2099 or Sun, 6 Jul 2099</td><td><a href="/races/100">Winter Criterium</a></td></tr></table>`)
+		case "/races/100":
+			fmt.Fprint(w, `<dl><dt>Date</dt><dd>Sunday, 6 July 2099</dd></dl>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Limiter = nil
+	src.Details = true
+	src.Log = log.New(&out, "", 0)
+	clubs := []calendar.Club{{ClubName: "Club", ClubURL: server.URL + "/calendar/club", State: "VIC"}}
+
+	result, err := src.FetchEvents(context.Background(), "VIC", clubs)
+	if err != nil || len(result.Events) != 1 {
+		t.Fatalf("FetchEvents = %+v, %v", result, err)
+	}
+	event := result.Events[0]
+	if event.EventDate != "2099-07-06T00:00:00Z" || event.DateConfidence != 1 || event.DateMethod != "race page" {
+		t.Errorf("Date %s found by %s (%.2f), want 2099-07-06 by race page (1.00)", event.EventDate, event.DateMethod, event.DateConfidence)
+	}
+	if strings.Contains(out.String(), "Low confidence") {
+		t.Errorf("Expected no review of a date settled by the race page, got:\n%s", out.String())
+	}
+}
+
+func TestRaceDetailsCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/calendar/club":
+			fmt.Fprint(w, `<table><tr><td>Sat, 5 Jul 2099</td><td><a href="/races/100">Winter Criterium</a></td></tr></table>`)
+		case "/races/100":
+			requests++
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `<dl><dt>Start</dt><dd>8:30am</dd><dt>Venue</dt><dd>Casey Fields</dd></dl>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache", "races.json")
+	cache, err := OpenDetailsCache(cachePath, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Limiter = nil
+	src.Details = true
+	src.DetailsCache = cache
+	clubs := []calendar.Club{{ClubName: "Club", ClubURL: server.URL + "/calendar/club", State: "VIC"}}
+
+	fetch := func() calendar.Event {
+		t.Helper()
+		result, err := src.FetchEvents(context.Background(), "VIC", clubs)
+		if err != nil || len(result.Events) != 1 {
+			t.Fatalf("FetchEvents = %+v, %v", result, err)
+		}
+		return result.Events[0]
+	}
+
+	event := fetch()
+	if event.StartTime != "08:30" || event.Venue != "Casey Fields" {
+		t.Errorf("Details not applied: %+v", event)
+	}
+
+	// Unchanged listing within MaxAge: served from cache
+	fetch()
+	if requests != 1 {
+		t.Errorf("Expected cached details to be reused, got %d race page requests", requests)
+	}
+
+	// Expired entry: revalidated with the ETag and kept on 304
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	cache, err = OpenDetailsCache(cachePath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.DetailsCache = cache
+
+	event = fetch()
+	if requests != 2 {
+		t.Errorf("Expected a conditional request, got %d race page requests", requests)
+	}
+	if event.Venue != "Casey Fields" {
+		t.Errorf("Cached details lost after 304: %+v", event)
+	}
+}
@@ -0,0 +1,315 @@
+// Package entryboss scrapes clubs and events from EntryBoss (entryboss.cc).
+package entryboss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/ratelimit"
+	"racecalendar/pkg/retry"
+)
+
+// Name is the source name recorded on EntryBoss clubs and events.
+const Name = "EntryBoss"
+
+// DefaultBaseURL is the public EntryBoss site.
+const DefaultBaseURL = "https://entryboss.cc"
+
+// Source scrapes EntryBoss. It implements calendar.Source.
+type Source struct {
+	// BaseURL is the site to scrape, without a trailing slash. Club URLs
+	// stored against another host are rewritten to it, so a mirror or test
+	// server can stand in for EntryBoss.
+	BaseURL string
+
+	// Client makes every request. It should have a timeout.
+	Client *http.Client
+
+	// Limiter throttles requests per host. Nil means no limit.
+	Limiter *ratelimit.PerHost
+
+	// Concurrency is the number of club pages fetched at once.
+	Concurrency int
+
+	// Retry controls how failed page fetches are retried.
+	Retry retry.Policy
+
+	// Details enables a second pass that fetches each race page for its start
+	// time, venue, entries close date, grades and status.
+	Details bool
+
+	// DetailsCache, if set, avoids refetching race pages that have not changed.
+	DetailsCache *DetailsCache
+
+	Log calendar.Logger
+}
+
+// New returns an EntryBoss source for the public site using client. It
+// fetches one club at a time at no more than one request per second.
+func New(client *http.Client) *Source {
+	return &Source{
+		BaseURL:     DefaultBaseURL,
+		Client:      client,
+		Limiter:     ratelimit.NewPerHost(1, 1),
+		Concurrency: 1,
+		Retry:       retry.DefaultPolicy(),
+	}
+}
+
+// Name implements calendar.Source.
+func (s *Source) Name() string {
+	return Name
+}
+
+// DiscoverClubs parses the state sections of the EntryBoss club dropdown.
+func (s *Source) DiscoverClubs(ctx context.Context) ([]calendar.Club, error) {
+	doc, err := s.fetchDocument(ctx, s.BaseURL+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch main page: %w", err)
+	}
+
+	currentTime := time.Now().Format(time.RFC3339)
+	var clubs []calendar.Club
+	seen := make(map[string]bool)
+	var currentState string
+
+	// Parse dropdown menu for all state sections
+	doc.Find("li").Each(func(i int, li *goquery.Selection) {
+		// Check if this is a state header
+		if li.HasClass("dropdown-header") {
+			headerText := strings.ToUpper(strings.TrimSpace(li.Text()))
+			for _, state := range calendar.States {
+				if strings.Contains(headerText, state) {
+					currentState = state
+					calendar.Logf(s.Log, "Found %s section in dropdown\n", state)
+					return
+				}
+			}
+			// If we hit a non-state header, clear current state
+			currentState = ""
+		}
+
+		// If we're in a state section, look for club links
+		if currentState != "" {
+			li.Find("a[href*='/calendar/']").Each(func(j int, link *goquery.Selection) {
+				href, exists := link.Attr("href")
+				if !exists {
+					return
+				}
+
+				clubName := strings.TrimSpace(link.Text())
+				if clubName == "" {
+					return
+				}
+
+				fullURL := s.BaseURL + href
+				if seen[fullURL] {
+					return
+				}
+				seen[fullURL] = true
+				clubs = append(clubs, calendar.Club{
+					ClubName:    clubName,
+					ClubURL:     fullURL,
+					State:       currentState,
+					LastSeen:    currentTime,
+					Source:      Name,
+					Identifiers: map[string]string{Name: fullURL},
+				})
+				calendar.Logf(s.Log, "Found %s club: %s -> %s\n", currentState, clubName, fullURL)
+			})
+		}
+	})
+
+	return clubs, nil
+}
+
+// FetchEvents scrapes the calendar page of every club in the state that
+// EntryBoss lists, using up to Concurrency workers. Events are returned in
+// club order regardless of which fetch finishes first. Clubs that fail to scrape after retries are
+// reported in the result's Failures.
+func (s *Source) FetchEvents(ctx context.Context, state string, clubs []calendar.Club) (*calendar.Result, error) {
+	// Clubs only found on other sources have no calendar here
+	var listed []calendar.Club
+	for _, club := range clubs {
+		if club.Identifier(Name) != "" {
+			listed = append(listed, club)
+		}
+	}
+	clubs = listed
+	if len(clubs) == 0 {
+		return nil, calendar.ErrNoClubs
+	}
+
+	calendar.Logf(s.Log, "Found %d clubs in %s\n", len(clubs), state)
+
+	workers := s.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(clubs) {
+		workers = len(clubs)
+	}
+
+	// Each worker writes only its own club's slot, so no locking is needed
+	clubEvents := make([][]calendar.Event, len(clubs))
+	clubErrs := make([]error, len(clubs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				clubEvents[i], clubErrs[i] = s.fetchClub(ctx, clubs[i])
+			}
+		}()
+	}
+
+	for i := range clubs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &calendar.Result{}
+	for i, events := range clubEvents {
+		if err := clubErrs[i]; err != nil {
+			result.Failures = append(result.Failures, calendar.Failure{
+				Club:      clubs[i],
+				Err:       err,
+				Permanent: retry.Permanent(err),
+			})
+			continue
+		}
+		result.Events = append(result.Events, events...)
+	}
+	return result, nil
+}
+
+// fetchClub scrapes one club's events, logging any failure.
+func (s *Source) fetchClub(ctx context.Context, club calendar.Club) ([]calendar.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	calendar.Logf(s.Log, "Scraping events for %s...\n", club.ClubName)
+
+	events, err := s.scrapeClubEvents(ctx, club)
+	if err != nil {
+		if ctx.Err() == nil {
+			calendar.Logf(s.Log, "Failed to scrape events for %s: %v\n", club.ClubName, err)
+		}
+		return nil, err
+	}
+
+	for i := range events {
+		events[i].State = club.State
+		events[i].TimeZone = calendar.TimeZone(club.State)
+		events[i].Source = Name
+	}
+
+	if s.Details {
+		s.addDetails(ctx, events)
+
+		// The race page may have moved an event into the past
+		cutoff := calendar.Cutoff(club.State, time.Now())
+		upcoming := events[:0]
+		for _, event := range events {
+			if last := event.LastDate(); last != "" && last >= cutoff {
+				upcoming = append(upcoming, event)
+			}
+		}
+		events = upcoming
+	}
+
+	// Only dates the race pages did not settle are worth reviewing
+	for _, e := range events {
+		if e.DateConfidence < lowDateConfidence {
+			calendar.Logf(s.Log, "Low confidence (%.2f, %s) in date %s of %q (%s): review %s\n",
+				e.DateConfidence, e.DateMethod, e.EventDate, e.EventName, club.ClubName, e.EventURL)
+		}
+	}
+	return events, nil
+}
+
+// rebase points an absolute URL at BaseURL, keeping its path and query.
+func (s *Source) rebase(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() {
+		return s.BaseURL + rawURL
+	}
+	return s.BaseURL + u.RequestURI()
+}
+
+// fetchDocument fetches and parses a page, retrying transient failures.
+func (s *Source) fetchDocument(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	p, err := s.fetchPage(ctx, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.doc, nil
+}
+
+// page is a fetched HTML page. doc is nil if the server answered 304 Not Modified.
+type page struct {
+	doc    *goquery.Document
+	header http.Header
+}
+
+// fetchPage fetches and parses a page with extra request headers, such as
+// If-None-Match, retrying transient failures.
+func (s *Source) fetchPage(ctx context.Context, pageURL string, header http.Header) (*page, error) {
+	p := &page{}
+	err := s.Retry.Do(ctx, func() error {
+		if err := s.Limiter.WaitURL(ctx, pageURL); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		p.header = resp.Header
+		if resp.StatusCode == http.StatusNotModified {
+			return nil
+		}
+		if err := retry.CheckResponse(resp); err != nil {
+			return err
+		}
+
+		p.doc, err = goquery.NewDocumentFromReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
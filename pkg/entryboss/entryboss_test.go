@@ -0,0 +1,196 @@
+package entryboss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/retry"
+)
+
+const homePageHTML = `<html><body><ul class="dropdown-menu">
+<li class="dropdown-header">VIC</li>
+<li><a href="/calendar/brunswick">Brunswick Cycling Club</a></li>
+<li class="dropdown-header">NSW</li>
+<li><a href="/calendar/sydney">Sydney Cycling Club</a></li>
+<li class="dropdown-header">Other</li>
+<li><a href="/calendar/overseas">Overseas Club</a></li>
+</ul></body></html>`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, homePageHTML)
+	})
+	mux.HandleFunc("/calendar/brunswick", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<table><tr><td>Sat, 5 Jul 2099</td><td><a href="/races/100">Winter Criterium</a></td></tr></table>`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDiscoverClubs(t *testing.T) {
+	server := newTestServer(t)
+	src := New(server.Client())
+	src.BaseURL = server.URL
+
+	clubs, err := src.DiscoverClubs(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverClubs failed: %v", err)
+	}
+
+	if len(clubs) != 2 {
+		t.Fatalf("Expected 2 clubs, got %d: %+v", len(clubs), clubs)
+	}
+	want := map[string]string{
+		server.URL + "/calendar/brunswick": "VIC",
+		server.URL + "/calendar/sydney":    "NSW",
+	}
+	for _, club := range clubs {
+		if want[club.ClubURL] != club.State {
+			t.Errorf("Unexpected club %+v", club)
+		}
+		if club.Source != Name || club.LastSeen == "" {
+			t.Errorf("Club missing source or lastSeen: %+v", club)
+		}
+	}
+}
+
+func TestFetchEventsRebasesClubURLs(t *testing.T) {
+	server := newTestServer(t)
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Limiter = nil
+
+	clubs := []calendar.Club{
+		{ClubName: "Brunswick Cycling Club", ClubURL: DefaultBaseURL + "/calendar/brunswick", State: "VIC"},
+		{ClubName: "Missing Club", ClubURL: DefaultBaseURL + "/calendar/missing", State: "VIC"},
+	}
+
+	result, err := src.FetchEvents(context.Background(), "VIC", clubs)
+	if err != nil {
+		t.Fatalf("FetchEvents failed: %v", err)
+	}
+
+	if len(result.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %d: %+v", len(result.Events), result.Events)
+	}
+	event := result.Events[0]
+	if event.EventURL != server.URL+"/races/100" {
+		t.Errorf("EventURL = %q, want %q", event.EventURL, server.URL+"/races/100")
+	}
+	if event.State != "VIC" || event.Source != Name {
+		t.Errorf("Event missing state or source: %+v", event)
+	}
+
+	if len(result.Failures) != 1 {
+		t.Fatalf("Expected 1 failure, got %+v", result.Failures)
+	}
+	if failure := result.Failures[0]; failure.Club.ClubName != "Missing Club" || !failure.Permanent {
+		t.Errorf("Expected a permanent failure for the missing club, got %+v", failure)
+	}
+}
+
+func TestFetchEventsRetriesTransientFailures(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<table><tr><td>Sat, 5 Jul 2099</td><td><a href="/races/100">Winter Criterium</a></td></tr></table>`)
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Limiter = nil
+	src.Retry = retry.Policy{Retries: 2, BaseDelay: time.Millisecond}
+
+	clubs := []calendar.Club{{ClubName: "Flaky Club", ClubURL: server.URL + "/calendar/flaky", State: "VIC"}}
+	result, err := src.FetchEvents(context.Background(), "VIC", clubs)
+	if err != nil {
+		t.Fatalf("FetchEvents failed: %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	if len(result.Events) != 1 || len(result.Failures) != 0 {
+		t.Errorf("Expected the retry to succeed, got events=%+v failures=%+v", result.Events, result.Failures)
+	}
+}
+
+func TestFetchEventsWithoutClubs(t *testing.T) {
+	src := New(http.DefaultClient)
+
+	if _, err := src.FetchEvents(context.Background(), "NT", nil); err != calendar.ErrNoClubs {
+		t.Errorf("Expected ErrNoClubs, got %v", err)
+	}
+}
+
+func TestFetchEventsConcurrentKeepsClubOrder(t *testing.T) {
+	const clubCount = 20
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/calendar/club-%d", &n)
+		// Later clubs answer first
+		time.Sleep(time.Duration(clubCount-n) * time.Millisecond)
+		fmt.Fprintf(w, `<table><tr><td>Sat, 5 Jul 2099</td><td><a href="/races/%d">Club %d Race</a></td></tr></table>`, n, n)
+	}))
+	defer server.Close()
+
+	src := New(server.Client())
+	src.BaseURL = server.URL
+	src.Limiter = nil
+	src.Concurrency = 8
+
+	var clubs []calendar.Club
+	for i := 0; i < clubCount; i++ {
+		clubs = append(clubs, calendar.Club{
+			ClubName: fmt.Sprintf("Club %d", i),
+			ClubURL:  fmt.Sprintf("%s/calendar/club-%d", server.URL, i),
+			State:    "VIC",
+		})
+	}
+
+	result, err := src.FetchEvents(context.Background(), "VIC", clubs)
+	if err != nil {
+		t.Fatalf("FetchEvents failed: %v", err)
+	}
+
+	if len(result.Events) != clubCount {
+		t.Fatalf("Expected %d events, got %d", clubCount, len(result.Events))
+	}
+	for i, event := range result.Events {
+		if event.ClubName != clubs[i].ClubName {
+			t.Errorf("Event %d belongs to %q, want %q", i, event.ClubName, clubs[i].ClubName)
+		}
+	}
+}
+
+func TestFetchEventsCancelled(t *testing.T) {
+	server := newTestServer(t)
+	src := New(server.Client())
+	src.BaseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clubs := []calendar.Club{{ClubName: "Brunswick Cycling Club", ClubURL: server.URL + "/calendar/brunswick", State: "VIC"}}
+	if _, err := src.FetchEvents(ctx, "VIC", clubs); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
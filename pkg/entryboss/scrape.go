@@ -0,0 +1,158 @@
+package entryboss
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/calendar"
+)
+
+func (s *Source) scrapeClubEvents(ctx context.Context, club calendar.Club) ([]calendar.Event, error) {
+	doc, err := s.fetchDocument(ctx, s.rebase(club.Identifier(Name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch club page: %w", err)
+	}
+
+	return parseClubEvents(doc, club, s.BaseURL, time.Now()), nil
+}
+
+// parseClubEvents extracts upcoming race links from a club calendar page.
+// Event URLs are resolved against baseURL. Where a race is linked more than
+// once, the date found with the most confidence is kept, and the longest link
+// text as its name, since buttons such as "Enter" link to the same page.
+// Links that are not races at all are left to the Updater's filters.
+func parseClubEvents(doc *goquery.Document, club calendar.Club, baseURL string, now time.Time) []calendar.Event {
+	var events []calendar.Event
+
+	// Include events from yesterday onwards, in the club's local time
+	cutoff := calendar.Cutoff(club.State, now)
+
+	addEvent := func(eventName string, found foundDate, href string) {
+		event := calendar.Event{
+			EventName:      eventName,
+			EventDate:      found.date,
+			EndDate:        found.endDate,
+			DateConfidence: found.confidence,
+			DateMethod:     found.method,
+			ClubName:       club.ClubName,
+			EventURL:       baseURL + href,
+		}
+		event.ID = calendar.EventID(event) // e.g. "entryboss-28757"
+		// Keep multi-day events until their last day has passed
+		if last := event.LastDate(); last != "" && last >= cutoff {
+			events = append(events, event)
+		}
+	}
+
+	// Method 1: Look for event links in standard format
+	doc.Find("a[href*='/races/']").Each(func(i int, link *goquery.Selection) {
+		href, exists := link.Attr("href")
+		if !exists {
+			return
+		}
+
+		eventName := strings.TrimSpace(link.Text())
+		if eventName == "" {
+			return
+		}
+
+		// Try to extract date information from nearby elements
+		if found, ok := extractEventDate(link, now); ok {
+			addEvent(eventName, found, href)
+		}
+	})
+
+	// Method 2: Look for table-based event listings (like Northern Combine)
+	doc.Find("table tr, .fixture-row, .event-row").Each(func(i int, row *goquery.Selection) {
+		// Look for date patterns in the row. A row with several dates may
+		// list more than one race, so its first date is less certain.
+		dates := datesIn(spacedText(row), now)
+		if len(dates) == 0 {
+			return
+		}
+		confidence := 0.9
+		if len(dates) > 1 {
+			confidence = 0.45
+		}
+
+		// Look for race links in this row
+		row.Find("a[href*='/races/']").Each(func(j int, link *goquery.Selection) {
+			href, exists := link.Attr("href")
+			if !exists {
+				return
+			}
+
+			eventName := strings.TrimSpace(link.Text())
+			if eventName == "" {
+				return
+			}
+
+			found := foundDate{dates[0][0], dates[0][1], confidence, "row"}
+			if hasOtherRaces(row, href) {
+				found.confidence = math.Round(found.confidence*0.6*100) / 100
+			}
+			addEvent(eventName, found, href)
+		})
+	})
+
+	// Method 3: Look for events in "Upcoming" sections
+	doc.Find("h3, h4, .section-header").Each(func(i int, header *goquery.Selection) {
+		headerText := strings.ToLower(strings.TrimSpace(header.Text()))
+		if !strings.Contains(headerText, "upcoming") && !strings.Contains(headerText, "fixture") {
+			return
+		}
+
+		// Process the next few sibling elements for events
+		current := header.Next()
+		for j := 0; j < 10 && current.Length() > 0; j++ {
+			current.Find("a[href*='/races/']").Each(func(k int, link *goquery.Selection) {
+				href, exists := link.Attr("href")
+				if !exists {
+					return
+				}
+
+				eventName := strings.TrimSpace(link.Text())
+				if eventName == "" {
+					return
+				}
+
+				if found, ok := extractEventDate(link, now); ok {
+					addEvent(eventName, found, href)
+				}
+			})
+			current = current.Next()
+		}
+	})
+
+	// Remove duplicates based on event URL, keeping the most confident
+	// match, or the last of equally confident ones
+	uniqueEvents := make(map[string]calendar.Event)
+	names := make(map[string]string)
+	var order []string
+	for _, event := range events {
+		kept, seen := uniqueEvents[event.EventURL]
+		if !seen {
+			order = append(order, event.EventURL)
+		}
+		if !seen || event.DateConfidence >= kept.DateConfidence {
+			uniqueEvents[event.EventURL] = event
+		}
+		if len(event.EventName) > len(names[event.EventURL]) {
+			names[event.EventURL] = event.EventName
+		}
+	}
+
+	events = make([]calendar.Event, 0, len(uniqueEvents))
+	for _, url := range order {
+		event := uniqueEvents[url]
+		event.EventName = names[url]
+		events = append(events, event)
+	}
+
+	return events
+}
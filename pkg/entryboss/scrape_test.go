@@ -0,0 +1,79 @@
+package entryboss
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"racecalendar/pkg/calendar"
+)
+
+const clubPageHTML = `<html><body>
+<h3>Upcoming Events</h3>
+<table>
+  <tr><td>Sat, 5 Jul 2025</td><td><a href="/races/100">Winter Criterium</a></td><td><a href="/races/100">Enter</a></td></tr>
+  <tr><td>Sun, 6 Jul 2025</td><td><a href="/races/101">2025 Season Pass</a></td></tr>
+  <tr><td>Sun, 1 Jun 2025</td><td><a href="/races/99">Autumn Road Race</a></td></tr>
+  <tr><td>Sat, 12 Jul 2025</td><td><a href="/races/102"><img src="/enter.png"></a></td></tr>
+</table>
+</body></html>`
+
+func TestParseClubEvents(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(clubPageHTML))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	club := calendar.Club{ClubName: "Test Club", ClubURL: "https://entryboss.cc/calendar/test", State: "VIC"}
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	events := parseClubEvents(doc, club, DefaultBaseURL, now)
+
+	// The season pass is left to the Updater's filters; the past race and
+	// the link without text are not events
+	if len(events) != 2 || events[1].EventName != "2025 Season Pass" {
+		t.Fatalf("Expected Winter Criterium and the season pass, got %d: %+v", len(events), events)
+	}
+	// Named by its title rather than its "Enter" button
+	event := events[0]
+	if event.EventName != "Winter Criterium" {
+		t.Errorf("EventName = %q, want %q", event.EventName, "Winter Criterium")
+	}
+	if event.EventDate != "2025-07-05T00:00:00Z" {
+		t.Errorf("EventDate = %q, want %q", event.EventDate, "2025-07-05T00:00:00Z")
+	}
+	if event.EventURL != "https://entryboss.cc/races/100" {
+		t.Errorf("EventURL = %q, want %q", event.EventURL, "https://entryboss.cc/races/100")
+	}
+	if event.ClubName != club.ClubName {
+		t.Errorf("ClubName = %q, want %q", event.ClubName, club.ClubName)
+	}
+	// Found in its table row more surely than by climbing from its link
+	if event.DateMethod != "row" || event.DateConfidence != 0.9 {
+		t.Errorf("Date found by %s (%.2f), want row (0.90)", event.DateMethod, event.DateConfidence)
+	}
+}
+
+func TestParseClubEventsKeepsEventsInProgress(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><table>
+  <tr><td>Fri 27 Jun – Tue 1 Jul 2025</td><td><a href="/races/200">Winter Tour</a></td></tr>
+  <tr><td>20–22 Jun 2025</td><td><a href="/races/199">Autumn Carnival</a></td></tr>
+</table></body></html>`))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	club := calendar.Club{ClubName: "Test Club", State: "VIC"}
+	now := time.Date(2025, 6, 29, 0, 0, 0, 0, time.UTC)
+
+	events := parseClubEvents(doc, club, DefaultBaseURL, now)
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d: %+v", len(events), events)
+	}
+	if events[0].EventDate != "2025-06-27T00:00:00Z" || events[0].EndDate != "2025-07-01T00:00:00Z" {
+		t.Errorf("Winter Tour dates = %q to %q, want 2025-06-27 to 2025-07-01", events[0].EventDate, events[0].EndDate)
+	}
+}
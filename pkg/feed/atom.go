@@ -0,0 +1,158 @@
+// Package feed publishes Atom feeds of newly listed events, so riders can
+// follow new races in a feed reader or a Slack RSS app instead of checking
+// the site.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	NS      string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Category  *atomTerm   `xml:"category"`
+	Summary   atomSummary `xml:"summary"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomSummary struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Feed is one Atom document.
+type Feed struct {
+	ID      string // a tag: URI that never changes
+	Title   string
+	SelfURL string // where the feed is published
+	SiteURL string
+	Entries []Entry // newest first
+}
+
+// encode writes the feed as Atom. Its updated time is that of the newest
+// entry, so an unchanged feed encodes identically.
+func (f Feed) encode(w io.Writer) error {
+	doc := atomFeed{
+		NS:     atomNS,
+		ID:     f.ID,
+		Title:  f.Title,
+		Author: atomAuthor{Name: "Racing Calendar"},
+		Links: []atomLink{
+			{Href: f.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.SiteURL, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	var updated time.Time
+	for _, e := range f.Entries {
+		if e.FirstSeen().After(updated) {
+			updated = e.FirstSeen()
+		}
+		doc.Entries = append(doc.Entries, e.atom())
+	}
+	doc.Updated = updated.UTC().Format(time.RFC3339)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode feed %s: %w", f.ID, err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (e Entry) atom() atomEntry {
+	seen := e.FirstSeen().UTC().Format(time.RFC3339)
+	entry := atomEntry{
+		ID:        entryID(e),
+		Title:     fmt.Sprintf("%s (%s)", e.Event.EventName, displayDate(e.Event)),
+		Published: seen,
+		Updated:   seen,
+		Summary:   atomSummary{Type: "text", Text: summary(e)},
+	}
+	if e.Event.EventURL != "" {
+		entry.Links = []atomLink{{Href: e.Event.EventURL, Rel: "alternate"}}
+	}
+	if e.Event.Category != "" {
+		entry.Category = &atomTerm{Term: e.Event.Category}
+	}
+	return entry
+}
+
+// entryID is a tag: URI for the event, from its calendar.EventID like the
+// UIDs of the calendars. It stays the same if the event is listed again, so
+// readers do not show it twice.
+func entryID(e Entry) string {
+	return fmt.Sprintf("tag:%s,2025:event/%s", tagAuthority, calendar.EventID(e.Event))
+}
+
+func summary(e Entry) string {
+	lines := []string{
+		fmt.Sprintf("%s, %s", e.Event.ClubName, e.Event.State),
+		"Date: " + displayDate(e.Event),
+	}
+	if e.Event.StartTime != "" {
+		lines = append(lines, "Start: "+e.Event.StartTime)
+	}
+	if e.Event.Venue != "" {
+		lines = append(lines, "Venue: "+e.Event.Venue)
+	}
+	lines = append(lines, "First seen: "+e.FirstSeen().UTC().Format(time.RFC3339))
+	return strings.Join(lines, "\n")
+}
+
+// displayDate formats the event's date, or range of dates, as "Sat 5 Jul 2025".
+func displayDate(e calendar.Event) string {
+	if len(e.EventDate) < 10 {
+		return e.EventDate
+	}
+	start, err := time.Parse("2006-01-02", e.EventDate[:10])
+	if err != nil {
+		return e.EventDate
+	}
+	text := start.Format("Mon 2 Jan 2006")
+	if last := e.LastDate(); last != e.EventDate[:10] {
+		if end, err := time.Parse("2006-01-02", last); err == nil {
+			text += " – " + end.Format("Mon 2 Jan 2006")
+		}
+	}
+	return text
+}
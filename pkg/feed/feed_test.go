@@ -0,0 +1,122 @@
+package feed
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+func TestNewEvents(t *testing.T) {
+	previous := []calendar.Event{
+		{EventName: "Winter Criterium", EventURL: "https://entryboss.cc/races/100"},
+		{EventName: "Renamed Race", EventURL: "https://entryboss.cc/races/101"},
+	}
+	current := []calendar.Event{
+		{EventName: "Winter Criterium", EventURL: "https://entryboss.cc/races/100"},
+		{EventName: "Renamed Race (A Grade)", EventURL: "https://entryboss.cc/races/101"},
+		{EventName: "Spring Road Race", EventURL: "https://entryboss.cc/races/102"},
+	}
+
+	added := NewEvents(previous, current)
+	if len(added) != 1 || added[0].EventName != "Spring Road Race" {
+		t.Errorf("Expected only Spring Road Race to be new, got %+v", added)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	store := calendar.NewStore(t.TempDir())
+	dir := filepath.Join(t.TempDir(), "feeds")
+	day1 := time.Date(2025, 7, 1, 6, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	known := calendar.Event{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", ClubName: "Test Club", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100"}
+	fresh := calendar.Event{EventName: "Spring Road Race", EventDate: "2025-09-06T00:00:00Z", ClubName: "Other Club", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/200"}
+	if err := store.SaveEvents("VIC", []calendar.Event{known, fresh}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveEvents("NSW", []calendar.Event{{EventName: "Brand New State", EventDate: "2025-07-05T00:00:00Z", State: "NSW", EventURL: "https://entryboss.cc/races/300"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{Store: store, Dir: dir, MaxAge: 24 * time.Hour}
+	previous := map[string][]calendar.Event{"VIC": {known}}
+	if err := g.Generate([]string{"VIC", "NSW"}, previous, day1); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "vic.atom"))
+	if err != nil {
+		t.Fatalf("Expected vic.atom: %v", err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("vic.atom is not valid XML: %v", err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Published != "2025-07-01T06:00:00Z" {
+		t.Fatalf("Expected one entry first seen on day 1, got %+v", feed.Entries)
+	}
+	if feed.Updated != "2025-07-01T06:00:00Z" {
+		t.Errorf("Feed updated = %q, want the newest entry's first seen", feed.Updated)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vic", "other-club.atom")); err != nil {
+		t.Errorf("Expected club feed: %v", err)
+	}
+	if want := "tag:racingcalendar.app,2025:event/entryboss-200"; feed.Entries[0].ID != want {
+		t.Errorf("Entry ID = %q, want %q", feed.Entries[0].ID, want)
+	}
+	// NSW had no previous events, so nothing is announced for it
+	if _, err := os.Stat(filepath.Join(dir, "nsw.atom")); err == nil {
+		t.Errorf("Did not expect nsw.atom on a state's first run")
+	}
+
+	// The next run sees no new events; the entry keeps its first seen time
+	previous = map[string][]calendar.Event{"VIC": {known, fresh}}
+	if err := g.Generate([]string{"VIC"}, previous, day1.Add(time.Hour)); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	again, _ := os.ReadFile(filepath.Join(dir, "vic.atom"))
+	if string(again) != string(data) {
+		t.Errorf("Feed changed without new events")
+	}
+
+	// Once past MaxAge the entry and its feeds are gone
+	if err := g.Generate([]string{"VIC"}, previous, day2); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vic.atom")); err == nil {
+		t.Errorf("Expected vic.atom to be removed once its entries expired")
+	}
+}
+
+func TestGenerateUsesEventFirstSeen(t *testing.T) {
+	store := calendar.NewStore(t.TempDir())
+	dir := filepath.Join(t.TempDir(), "feeds")
+	now := time.Date(2025, 7, 2, 6, 0, 0, 0, time.UTC)
+
+	known := calendar.Event{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", State: "VIC", EventURL: "https://entryboss.cc/races/100"}
+	fresh := calendar.Event{EventName: "Spring Road Race", EventDate: "2025-09-06T00:00:00Z", State: "VIC", EventURL: "https://entryboss.cc/races/200", FirstSeen: "2025-07-01T20:00:00Z"}
+	if err := store.SaveEvents("VIC", []calendar.Event{known, fresh}); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{Store: store, Dir: dir}
+	if err := g.Generate([]string{"VIC"}, map[string][]calendar.Event{"VIC": {known}}, now); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "vic.atom"))
+	if err != nil {
+		t.Fatalf("Expected vic.atom: %v", err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("vic.atom is not valid XML: %v", err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Published != fresh.FirstSeen {
+		t.Errorf("Expected one entry published when the event was first seen, got %+v", feed.Entries)
+	}
+}
@@ -0,0 +1,262 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+// DefaultSiteURL is where the site, and so the feeds, are published.
+const DefaultSiteURL = "https://racingcalendar.app"
+
+const tagAuthority = "racingcalendar.app"
+
+// EntriesFile is the name of the file inside a Generator's Dir that keeps
+// the events already announced, so feeds keep their history between runs.
+const EntriesFile = "entries.json"
+
+// Entry is a newly listed event, dated by when the event was first seen.
+type Entry struct {
+	Event calendar.Event `json:"event"`
+}
+
+// FirstSeen returns when the event was first seen, as recorded by
+// calendar.MergeEvents.
+func (e Entry) FirstSeen() time.Time {
+	t, _ := time.Parse(time.RFC3339, e.Event.FirstSeen)
+	return t
+}
+
+// Generator writes Atom feeds of events that appear in the current events
+// files but not in the previous ones:
+//
+//	<Dir>/<state>.atom         new events in a state
+//	<Dir>/<state>/<club>.atom  new events from one club
+//
+// Feeds without entries are not written, and old feeds are removed.
+type Generator struct {
+	Store   *calendar.Store
+	Dir     string
+	SiteURL string // defaults to DefaultSiteURL
+
+	// MaxAge is how long an event stays in the feeds after it is first seen.
+	MaxAge time.Duration
+
+	Log calendar.Logger
+}
+
+// Generate compares previous with the current events of each state, records
+// the new events and rewrites the feeds. previous maps each state to its
+// events before the update. A state with no previous events is taken as a
+// first run and announces nothing, rather than every event it has.
+func (g *Generator) Generate(states []string, previous map[string][]calendar.Event, now time.Time) error {
+	entries, err := g.loadEntries()
+	if err != nil {
+		return err
+	}
+
+	announced := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		announced[calendar.EventID(e.Event)] = true
+	}
+
+	added := 0
+	for _, state := range states {
+		current, err := g.Store.LoadEvents(state)
+		if err != nil {
+			return err
+		}
+		if len(previous[state]) == 0 {
+			continue
+		}
+
+		for _, e := range NewEvents(previous[state], current) {
+			if announced[calendar.EventID(e)] {
+				continue
+			}
+			announced[calendar.EventID(e)] = true
+			if e.FirstSeen == "" {
+				e.FirstSeen = now.Format(time.RFC3339)
+			}
+			entries = append(entries, Entry{Event: e})
+			added++
+		}
+	}
+
+	// Forget entries that have been in the feeds long enough
+	kept := entries[:0]
+	for _, e := range entries {
+		if g.MaxAge <= 0 || now.Sub(e.FirstSeen()) < g.MaxAge {
+			kept = append(kept, e)
+		}
+	}
+	entries = kept
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].FirstSeen().After(entries[j].FirstSeen())
+	})
+
+	feeds := g.feeds(entries)
+	for name, f := range feeds {
+		if err := g.write(name, f); err != nil {
+			return err
+		}
+	}
+	removed, err := g.removeStale(feeds)
+	if err != nil {
+		return err
+	}
+	if err := g.saveEntries(entries); err != nil {
+		return err
+	}
+
+	calendar.Logf(g.Log, "Found %d new events; wrote %d feeds to %s (%d removed)\n", added, len(feeds), g.Dir, removed)
+	return nil
+}
+
+// NewEvents returns the events in current that are not in previous, in the
+// order of current.
+func NewEvents(previous, current []calendar.Event) []calendar.Event {
+	seen := make(map[string]bool, len(previous))
+	for _, e := range previous {
+		seen[calendar.EventID(e)] = true
+	}
+
+	var added []calendar.Event
+	for _, e := range current {
+		if id := calendar.EventID(e); !seen[id] {
+			seen[id] = true
+			added = append(added, e)
+		}
+	}
+	return added
+}
+
+// feeds groups entries into a feed per state and per club, keyed by file name.
+func (g *Generator) feeds(entries []Entry) map[string]Feed {
+	siteURL := g.SiteURL
+	if siteURL == "" {
+		siteURL = DefaultSiteURL
+	}
+	siteURL = strings.TrimSuffix(siteURL, "/")
+	feedURL := siteURL + "/" + filepath.ToSlash(filepath.Base(g.Dir)) + "/"
+
+	feeds := make(map[string]Feed)
+	add := func(name, title string, e Entry) {
+		f, ok := feeds[name]
+		if !ok {
+			f = Feed{
+				ID:      fmt.Sprintf("tag:%s,2025:feeds/%s", tagAuthority, strings.TrimSuffix(name, ".atom")),
+				Title:   title,
+				SelfURL: feedURL + name,
+				SiteURL: siteURL + "/",
+			}
+		}
+		f.Entries = append(f.Entries, e)
+		feeds[name] = f
+	}
+
+	for _, e := range entries {
+		state := strings.ToLower(e.Event.State)
+		if state == "" {
+			continue
+		}
+		add(state+".atom", "New events: "+e.Event.State, e)
+		if slug := calendar.Slug(e.Event.ClubName); slug != "" {
+			add(state+"/"+slug+".atom", fmt.Sprintf("New events: %s (%s)", e.Event.ClubName, e.Event.State), e)
+		}
+	}
+	return feeds
+}
+
+func (g *Generator) loadEntries() ([]Entry, error) {
+	path := filepath.Join(g.Dir, EntriesFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func (g *Generator) saveEntries(entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal feed entries: %w", err)
+	}
+	return writeFile(filepath.Join(g.Dir, EntriesFile), data)
+}
+
+// write encodes f to name, leaving the file alone if it would not change.
+func (g *Generator) write(name string, f Feed) error {
+	var buf bytes.Buffer
+	if err := f.encode(&buf); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(g.Dir, filepath.FromSlash(name)), buf.Bytes())
+}
+
+// removeStale deletes .atom files under Dir that were not written this run.
+func (g *Generator) removeStale(feeds map[string]Feed) (int, error) {
+	if _, err := os.Stat(g.Dir); errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	var stale []string
+	err := filepath.WalkDir(g.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".atom" {
+			return err
+		}
+		rel, err := filepath.Rel(g.Dir, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := feeds[filepath.ToSlash(rel)]; !ok {
+			stale = append(stale, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", g.Dir, err)
+	}
+
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return len(stale), nil
+}
+
+// writeFile writes data to path unless it already holds exactly that.
+func writeFile(path string, data []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package ics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+// DefaultDir is where the calendars are written inside the site.
+const DefaultDir = "calendars"
+
+// SequencesFile is the name of the file inside an Exporter's Dir that tracks
+// event sequences between runs.
+const SequencesFile = "sequences.json"
+
+// Exporter writes subscribable calendars for the events in a Store:
+//
+//	<Dir>/<state>.ics                  every event in a state
+//	<Dir>/<state>/<club>.ics           one club's events
+//	<Dir>/discipline/<discipline>.ics  one discipline across all states
+//
+// Calendars that no longer have events are removed.
+type Exporter struct {
+	Store *calendar.Store
+	Dir   string
+	Log   calendar.Logger
+}
+
+// Export writes the calendars for states. The calendars shared by every
+// state, for each discipline, and the sequences are built from the events of
+// all states, so exporting some states leaves the others' calendars and
+// sequences as they were.
+func (x *Exporter) Export(states []string, now time.Time) error {
+	seqs, err := OpenSequences(filepath.Join(x.Dir, SequencesFile))
+	if err != nil {
+		return err
+	}
+
+	exporting := make(map[string]bool)
+	allStates := append([]string(nil), calendar.States...)
+	for _, state := range states {
+		lower := strings.ToLower(state)
+		if !exporting[lower] && !containsFold(calendar.States, state) {
+			allStates = append(allStates, state)
+		}
+		exporting[lower] = true
+	}
+
+	files := make(map[string]Calendar)
+	var all []calendar.Event
+	byDiscipline := make(map[string][]calendar.Event)
+
+	for _, state := range allStates {
+		events, err := x.Store.LoadEvents(state)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			continue
+		}
+		all = append(all, events...)
+		for _, e := range events {
+			if slug := calendar.Slug(e.Discipline); slug != "" {
+				byDiscipline[slug] = append(byDiscipline[slug], e)
+			}
+		}
+
+		lower := strings.ToLower(state)
+		if !exporting[lower] {
+			continue
+		}
+		files[lower+".ics"] = Calendar{Name: "Racing Calendar: " + state, Events: events}
+
+		byClub := make(map[string][]calendar.Event)
+		clubNames := make(map[string]string)
+		for _, e := range events {
+			if slug := calendar.Slug(e.ClubName); slug != "" {
+				byClub[slug] = append(byClub[slug], e)
+				clubNames[slug] = e.ClubName
+			}
+		}
+		for slug, clubEvents := range byClub {
+			files[filepath.Join(lower, slug+".ics")] = Calendar{Name: fmt.Sprintf("%s (%s)", clubNames[slug], state), Events: clubEvents}
+		}
+	}
+	for slug, events := range byDiscipline {
+		calendar.SortEvents(events)
+		files[filepath.Join("discipline", slug+".ics")] = Calendar{Name: "Racing Calendar: " + disciplineName(slug), Events: events}
+	}
+
+	bumped := seqs.Observe(all, now)
+
+	written := 0
+	for name, cal := range files {
+		changed, err := x.write(name, cal, seqs, now)
+		if err != nil {
+			return err
+		}
+		if changed {
+			written++
+		}
+	}
+
+	removed, err := x.removeStale(files, exporting)
+	if err != nil {
+		return err
+	}
+
+	if err := seqs.Save(); err != nil {
+		return err
+	}
+
+	calendar.Logf(x.Log, "Exported %d calendars to %s (%d changed, %d removed, %d events updated)\n", len(files), x.Dir, written, removed, bumped)
+	return nil
+}
+
+// write encodes cal to name, leaving the file alone if it would not change.
+func (x *Exporter) write(name string, cal Calendar, seqs *Sequences, now time.Time) (bool, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, cal, seqs, now); err != nil {
+		return false, fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+
+	path := filepath.Join(x.Dir, name)
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// removeStale deletes .ics files under Dir that were not part of this export,
+// among the discipline calendars and those of the states being exported.
+func (x *Exporter) removeStale(files map[string]Calendar, exporting map[string]bool) (int, error) {
+	if _, err := os.Stat(x.Dir); errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	var stale []string
+	err := filepath.WalkDir(x.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".ics" {
+			return err
+		}
+		rel, err := filepath.Rel(x.Dir, path)
+		if err != nil {
+			return err
+		}
+		owner := strings.TrimSuffix(strings.SplitN(filepath.ToSlash(rel), "/", 2)[0], ".ics")
+		if _, ok := files[rel]; !ok && (owner == "discipline" || exporting[owner]) {
+			stale = append(stale, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", x.Dir, err)
+	}
+
+	sort.Strings(stale)
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return 0, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return len(stale), nil
+}
+
+// disciplineName returns the display name of a discipline, e.g. "Time trial"
+// for "time-trial".
+func disciplineName(discipline string) string {
+	name := strings.ReplaceAll(discipline, "-", " ")
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+// Package ics writes events as iCalendar (RFC 5545) files that riders can
+// subscribe to from Google Calendar, Apple Calendar and the like.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+// Domain qualifies event UIDs, as RFC 5545 recommends. It must never change,
+// or every subscriber would see every event deleted and re-added.
+const Domain = "racingcalendar.app"
+
+const prodID = "-//Racing Calendar//racecalendar//EN"
+
+// Calendar is one .ics file.
+type Calendar struct {
+	Name   string // shown by calendar apps as the calendar's name
+	Events []calendar.Event
+}
+
+// UID returns the stable identifier of an event: its EntryBoss race number or
+// its Buncheur page, falling back to a hash of what identifies it.
+func UID(e calendar.Event) string {
+	return calendar.EventID(e) + "@" + Domain
+}
+
+// Encode writes cal as an iCalendar stream. seqs supplies each event's
+// SEQUENCE and DTSTAMP; events it does not know get 0 and now.
+func Encode(w io.Writer, cal Calendar, seqs *Sequences, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	l := &lineWriter{w: bw}
+
+	l.line("BEGIN:VCALENDAR")
+	l.line("VERSION:2.0")
+	l.line("PRODID:" + prodID)
+	l.line("CALSCALE:GREGORIAN")
+	l.line("METHOD:PUBLISH")
+	l.line("X-WR-CALNAME:" + escapeText(cal.Name))
+	l.line("REFRESH-INTERVAL;VALUE=DURATION:PT12H")
+	l.line("X-PUBLISHED-TTL:PT12H")
+
+	for _, tz := range timeZones(cal.Events) {
+		writeTimeZone(l, tz.name, tz.from, tz.to)
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range cal.Events {
+		uid := UID(e)
+		if seen[uid] || !hasDate(e) {
+			continue
+		}
+		seen[uid] = true
+		writeEvent(l, e, uid, seqs.get(uid, now))
+	}
+
+	l.line("END:VCALENDAR")
+	if l.err != nil {
+		return l.err
+	}
+	return bw.Flush()
+}
+
+// hasDate reports whether the event's date can be written.
+func hasDate(e calendar.Event) bool {
+	if len(e.EventDate) < 10 {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", e.EventDate[:10])
+	return err == nil
+}
+
+func writeEvent(l *lineWriter, e calendar.Event, uid string, seq sequence) {
+	l.line("BEGIN:VEVENT")
+	l.line("UID:" + uid)
+	l.line("DTSTAMP:" + seq.Changed.UTC().Format("20060102T150405Z"))
+	l.line(fmt.Sprintf("SEQUENCE:%d", seq.Sequence))
+
+	start, _ := time.Parse("2006-01-02", e.EventDate[:10])
+	last, _ := time.Parse("2006-01-02", e.LastDate())
+	if e.StartTime != "" && last.Equal(start) {
+		// A timed event with no known end; RFC 5545 takes it to end as it starts
+		l.line(fmt.Sprintf("DTSTART;TZID=%s:%s", eventZone(e), start.Format("20060102")+"T"+strings.ReplaceAll(e.StartTime, ":", "")+"00"))
+	} else {
+		// All-day events end on the day after the last day
+		l.line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		l.line("DTEND;VALUE=DATE:" + last.AddDate(0, 0, 1).Format("20060102"))
+	}
+
+	l.line("SUMMARY:" + escapeText(e.EventName))
+	if e.Venue != "" {
+		l.line("LOCATION:" + escapeText(e.Venue))
+	}
+	if e.EventURL != "" {
+		l.line("URL;VALUE=URI:" + e.EventURL)
+	}
+	l.line("DESCRIPTION:" + escapeText(description(e)))
+	if e.Category != "" {
+		l.line("CATEGORIES:" + escapeText(e.Category))
+	}
+	switch e.Status {
+	case "Cancelled":
+		l.line("STATUS:CANCELLED")
+	case "Postponed":
+		l.line("STATUS:TENTATIVE")
+	default:
+		l.line("STATUS:CONFIRMED")
+	}
+	l.line("TRANSP:TRANSPARENT")
+	l.line("END:VEVENT")
+}
+
+// description lists the details that have no property of their own.
+func description(e calendar.Event) string {
+	var lines []string
+	if e.ClubName != "" {
+		lines = append(lines, "Club: "+e.ClubName)
+	}
+	if e.Status != "" {
+		lines = append(lines, "Status: "+e.Status)
+	}
+	if e.EntriesClose != "" && len(e.EntriesClose) >= 10 {
+		lines = append(lines, "Entries close: "+e.EntriesClose[:10])
+	}
+	if len(e.Grades) > 0 {
+		lines = append(lines, "Grades: "+strings.Join(e.Grades, ", "))
+	}
+	if e.Stale {
+		lines = append(lines, "This event could not be confirmed with the club's calendar at the last update.")
+	}
+	if e.EventURL != "" {
+		lines = append(lines, e.EventURL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func eventZone(e calendar.Event) string {
+	if e.TimeZone != "" {
+		return e.TimeZone
+	}
+	if tz := calendar.TimeZone(e.State); tz != "" {
+		return tz
+	}
+	return "UTC"
+}
+
+type zoneRange struct {
+	name     string
+	from, to time.Time
+}
+
+// timeZones returns the zones used by timed events, each with the span of
+// dates it must describe, sorted by name.
+func timeZones(events []calendar.Event) []zoneRange {
+	ranges := make(map[string]*zoneRange)
+	for _, e := range events {
+		if e.StartTime == "" || !hasDate(e) || e.LastDate() != e.EventDate[:10] {
+			continue
+		}
+		date, _ := time.Parse("2006-01-02", e.EventDate[:10])
+		name := eventZone(e)
+		r, ok := ranges[name]
+		if !ok {
+			ranges[name] = &zoneRange{name: name, from: date, to: date}
+			continue
+		}
+		if date.Before(r.from) {
+			r.from = date
+		}
+		if date.After(r.to) {
+			r.to = date
+		}
+	}
+
+	zones := make([]zoneRange, 0, len(ranges))
+	for _, r := range ranges {
+		zones = append(zones, *r)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].name < zones[j].name })
+	return zones
+}
+
+// writeTimeZone writes a VTIMEZONE for the named zone, listing each offset
+// change between a year before from and the end of to's year. Listing the
+// transitions rather than deriving RRULEs keeps it correct for any zone the
+// tz database knows.
+func writeTimeZone(l *lineWriter, name string, from, to time.Time) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	start := time.Date(from.Year()-1, 1, 1, 0, 0, 0, 0, loc)
+	end := time.Date(to.Year()+1, 1, 1, 0, 0, 0, 0, loc)
+
+	l.line("BEGIN:VTIMEZONE")
+	l.line("TZID:" + name)
+
+	abbrev, offset := start.Zone()
+	writeObservance(l, start, abbrev, offset, offset, start.IsDST())
+	for t := start; t.Before(end); {
+		next := t.AddDate(0, 0, 1)
+		if _, o := next.Zone(); o != offset {
+			at := findTransition(t, next)
+			newAbbrev, newOffset := at.Zone()
+			// DTSTART is the wall-clock time of the change in the old offset
+			writeObservance(l, at.In(time.FixedZone("", offset)), newAbbrev, offset, newOffset, at.IsDST())
+			offset = newOffset
+		}
+		t = next
+	}
+	l.line("END:VTIMEZONE")
+}
+
+func writeObservance(l *lineWriter, start time.Time, abbrev string, from, to int, dst bool) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	l.line("BEGIN:" + kind)
+	l.line("DTSTART:" + start.Format("20060102T150405"))
+	l.line("TZOFFSETFROM:" + formatOffset(from))
+	l.line("TZOFFSETTO:" + formatOffset(to))
+	l.line("TZNAME:" + abbrev)
+	l.line("END:" + kind)
+}
+
+// findTransition returns the first minute in (lo, hi] with hi's offset.
+func findTransition(lo, hi time.Time) time.Time {
+	_, want := hi.Zone()
+	for hi.Sub(lo) > time.Minute {
+		mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Minute)
+		if _, o := mid.Zone(); o == want {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// escapeText escapes a TEXT value (RFC 5545 section 3.3.11).
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// lineWriter writes content lines with CRLF endings, folding them at 75
+// octets without splitting UTF-8 sequences. The first error sticks.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (l *lineWriter) line(s string) {
+	if l.err != nil {
+		return
+	}
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 { // inside a UTF-8 sequence
+			cut--
+		}
+		if _, l.err = l.w.WriteString(s[:cut] + "\r\n "); l.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	_, l.err = l.w.WriteString(s + "\r\n")
+}
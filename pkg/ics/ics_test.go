@@ -0,0 +1,225 @@
+package ics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+func TestUID(t *testing.T) {
+	testCases := []struct {
+		event calendar.Event
+		want  string
+	}{
+		{calendar.Event{Source: "EntryBoss", EventURL: "https://entryboss.cc/races/28757"}, "entryboss-28757@racingcalendar.app"},
+		{calendar.Event{EventURL: "https://entryboss.cc/races/100"}, "entryboss-100@racingcalendar.app"},
+		{calendar.Event{Source: "Buncheur", EventURL: "https://www.buncheur.com/manly-warringah-cc-friday-night"}, "buncheur-manly-warringah-cc-friday-night@racingcalendar.app"},
+	}
+	for _, tc := range testCases {
+		if got := UID(tc.event); got != tc.want {
+			t.Errorf("UID(%+v) = %q, want %q", tc.event, got, tc.want)
+		}
+	}
+
+	fallback := calendar.Event{EventName: "Club Champs", EventDate: "2025-07-05T00:00:00Z", ClubName: "Test Club"}
+	if UID(fallback) != UID(fallback) || !strings.HasPrefix(UID(fallback), "event-") {
+		t.Errorf("Fallback UID not stable: %q", UID(fallback))
+	}
+}
+
+func TestEncode(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	cal := Calendar{Name: "Test, Calendar", Events: []calendar.Event{
+		{EventName: "Winter Criterium; A Grade", EventDate: "2025-07-05T00:00:00Z", ClubName: "Test Club", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100", StartTime: "08:30", TimeZone: "Australia/Melbourne", Venue: "Casey Fields"},
+		{EventName: "Winter Tour", EventDate: "2025-07-05T00:00:00Z", EndDate: "2025-07-07T00:00:00Z", ClubName: "Test Club", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/101", Status: "Cancelled"},
+		{EventName: "Duplicate", EventDate: "2025-07-05T00:00:00Z", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100"},
+		{EventName: "No Date", EventDate: "TBC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/102"},
+	}}
+
+	var buf strings.Builder
+	if err := Encode(&buf, cal, nil, now); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Test\\, Calendar\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Australia/Melbourne\r\n",
+		// Melbourne leaves daylight saving at 3am on the first Sunday in April
+		"BEGIN:STANDARD\r\nDTSTART:20250406T030000\r\nTZOFFSETFROM:+1100\r\nTZOFFSETTO:+1000\r\n",
+		"DTSTART;TZID=Australia/Melbourne:20250705T083000\r\n",
+		"SUMMARY:Winter Criterium\\; A Grade\r\n",
+		"LOCATION:Casey Fields\r\n",
+		"URL;VALUE=URI:https://entryboss.cc/races/100\r\n",
+		"DTSTAMP:20250701T000000Z\r\nSEQUENCE:0\r\n",
+		"DTSTART;VALUE=DATE:20250705\r\nDTEND;VALUE=DATE:20250708\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	var buf strings.Builder
+	cal := Calendar{Name: strings.Repeat("é", 60)}
+	if err := Encode(&buf, cal, nil, time.Now()); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	if !strings.Contains(unfolded, "X-WR-CALNAME:"+strings.Repeat("é", 60)+"\r\n") {
+		t.Errorf("Folding split a character or lost text:\n%s", buf.String())
+	}
+}
+
+func TestSequencesBumpOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SequencesFile)
+	day1 := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	event := calendar.Event{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100"}
+	uid := UID(event)
+
+	seqs, err := OpenSequences(path)
+	if err != nil {
+		t.Fatalf("OpenSequences failed: %v", err)
+	}
+	seqs.Observe([]calendar.Event{event}, day1)
+	if err := seqs.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	seqs, err = OpenSequences(path)
+	if err != nil {
+		t.Fatalf("OpenSequences failed: %v", err)
+	}
+	if bumped := seqs.Observe([]calendar.Event{event}, day2); bumped != 0 {
+		t.Errorf("Unchanged event bumped")
+	}
+	if seq := seqs.get(uid, day2); seq.Sequence != 0 || !seq.Changed.Equal(day1) {
+		t.Errorf("Unchanged event = %+v, want sequence 0 changed %v", seq, day1)
+	}
+
+	event.StartTime = "09:00"
+	if bumped := seqs.Observe([]calendar.Event{event}, day2); bumped != 1 {
+		t.Errorf("Changed event not bumped")
+	}
+	if seq := seqs.get(uid, day2); seq.Sequence != 1 || !seq.Changed.Equal(day2) {
+		t.Errorf("Changed event = %+v, want sequence 1 changed %v", seq, day2)
+	}
+}
+
+func TestSequencesKeptUntilEventIsOver(t *testing.T) {
+	day1 := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	event := calendar.Event{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100"}
+	uid := UID(event)
+
+	seqs, err := OpenSequences(filepath.Join(t.TempDir(), SequencesFile))
+	if err != nil {
+		t.Fatalf("OpenSequences failed: %v", err)
+	}
+	seqs.Observe([]calendar.Event{event}, day1)
+	event.StartTime = "09:00"
+	seqs.Observe([]calendar.Event{event}, day1)
+
+	// Delisted for a day, then listed again with the same details
+	seqs.Observe(nil, day1.AddDate(0, 0, 1))
+	if bumped := seqs.Observe([]calendar.Event{event}, day1.AddDate(0, 0, 2)); bumped != 0 {
+		t.Errorf("Relisted event bumped")
+	}
+	if seq := seqs.get(uid, day1); seq.Sequence != 1 {
+		t.Errorf("Relisted event sequence = %d, want 1", seq.Sequence)
+	}
+
+	// Once the event is over it is forgotten
+	seqs.Observe(nil, day1.AddDate(0, 0, 7))
+	if _, ok := seqs.entries[uid]; ok {
+		t.Errorf("Expected the sequence of a past event to be dropped")
+	}
+}
+
+func TestExport(t *testing.T) {
+	store := calendar.NewStore(t.TempDir())
+	dir := filepath.Join(t.TempDir(), "calendars")
+	events := []calendar.Event{
+		{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", ClubName: "Northern Combine CC", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100", Category: "Criterium", Discipline: "criterium"},
+		{EventName: "Road Race", EventDate: "2025-07-06T00:00:00Z", ClubName: "Test Club", State: "VIC", Source: "Buncheur", EventURL: "https://www.buncheur.com/test-club-road-race", Category: "Road Race", Discipline: "road"},
+	}
+	if err := store.SaveEvents("VIC", events); err != nil {
+		t.Fatalf("SaveEvents failed: %v", err)
+	}
+
+	// A calendar left over from a club that no longer has events
+	if err := os.MkdirAll(filepath.Join(dir, "vic"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vic", "old-club.ics"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := &Exporter{Store: store, Dir: dir}
+	if err := exporter.Export([]string{"VIC", "NSW"}, time.Now()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	for _, name := range []string{"vic.ics", "vic/northern-combine-cc.ics", "vic/test-club.ics", "discipline/criterium.ics", "discipline/road.ics", SequencesFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"nsw.ics", "vic/old-club.ics"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("Did not expect %s", name)
+		}
+	}
+}
+
+func TestExportOneStateKeepsOthers(t *testing.T) {
+	store := calendar.NewStore(t.TempDir())
+	dir := filepath.Join(t.TempDir(), "calendars")
+	vic := calendar.Event{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", ClubName: "Northern Combine CC", State: "VIC", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/100", Discipline: "criterium"}
+	nsw := calendar.Event{EventName: "Harbour Road Race", EventDate: "2025-07-06T00:00:00Z", ClubName: "Sydney CC", State: "NSW", Source: "EntryBoss", EventURL: "https://entryboss.cc/races/200", Discipline: "road"}
+	if err := store.SaveEvents("VIC", []calendar.Event{vic}); err != nil {
+		t.Fatalf("SaveEvents failed: %v", err)
+	}
+	if err := store.SaveEvents("NSW", []calendar.Event{nsw}); err != nil {
+		t.Fatalf("SaveEvents failed: %v", err)
+	}
+
+	exporter := &Exporter{Store: store, Dir: dir}
+	if err := exporter.Export(calendar.States, time.Now()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if err := exporter.Export([]string{"VIC"}, time.Now()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	for _, name := range []string{"vic.ics", "nsw.ics", "nsw/sydney-cc.ics", "discipline/criterium.ics", "discipline/road.ics"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+
+	seqs, err := OpenSequences(filepath.Join(dir, SequencesFile))
+	if err != nil {
+		t.Fatalf("OpenSequences failed: %v", err)
+	}
+	if _, ok := seqs.entries[UID(nsw)]; !ok {
+		t.Errorf("Sequence of the NSW event was forgotten")
+	}
+}
@@ -0,0 +1,124 @@
+package ics
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+// Sequences remembers what each exported event looked like, so that its
+// SEQUENCE can be bumped when its details change. Calendar apps ignore an
+// updated event whose SEQUENCE has not gone up.
+type Sequences struct {
+	path    string
+	entries map[string]sequence
+}
+
+type sequence struct {
+	Sequence int       `json:"sequence"`
+	Hash     string    `json:"hash"`
+	Changed  time.Time `json:"changed"` // written as DTSTAMP
+
+	// State and LastDate say when the event is over, so that the sequence of
+	// an event that drops off its listing for a while is kept until then.
+	State    string `json:"state,omitempty"`
+	LastDate string `json:"lastDate,omitempty"`
+}
+
+// OpenSequences loads the sequences stored at path. A missing file gives an
+// empty set.
+func OpenSequences(path string) (*Sequences, error) {
+	s := &Sequences{path: path, entries: make(map[string]sequence)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Observe records the current state of events. An event whose details differ
+// from last time gets the next SEQUENCE; events not seen before start at 0.
+// Events missing from events are remembered until they are over, so one that
+// is delisted and listed again carries on from its last SEQUENCE. It returns
+// the number of events whose SEQUENCE was bumped.
+func (s *Sequences) Observe(events []calendar.Event, now time.Time) int {
+	bumped := 0
+	current := make(map[string]sequence, len(events))
+	for _, e := range events {
+		uid := UID(e)
+		if _, done := current[uid]; done {
+			continue
+		}
+
+		hash := detailsHash(e)
+		seq, known := s.entries[uid]
+		switch {
+		case !known:
+			seq = sequence{Hash: hash, Changed: now}
+		case seq.Hash != hash:
+			seq = sequence{Sequence: seq.Sequence + 1, Hash: hash, Changed: now}
+			bumped++
+		}
+		seq.State, seq.LastDate = e.State, e.LastDate()
+		current[uid] = seq
+	}
+
+	for uid, seq := range s.entries {
+		if _, seen := current[uid]; !seen && seq.LastDate >= calendar.Cutoff(seq.State, now) {
+			current[uid] = seq
+		}
+	}
+	s.entries = current
+	return bumped
+}
+
+// Save writes the sequences back to their file.
+func (s *Sequences) Save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sequences: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(s.path), err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// get returns the sequence of uid, or 0 changed at now if it is unknown.
+func (s *Sequences) get(uid string, now time.Time) sequence {
+	if s != nil {
+		if seq, ok := s.entries[uid]; ok {
+			return seq
+		}
+	}
+	return sequence{Changed: now}
+}
+
+// detailsHash covers every field that is written to the calendar.
+func detailsHash(e calendar.Event) string {
+	sum := sha1.Sum([]byte(strings.Join([]string{
+		e.EventName, e.EventDate, e.EndDate, e.StartTime, eventZone(e),
+		e.ClubName, e.EventURL, e.Category, e.Venue, e.EntriesClose,
+		strings.Join(e.Grades, ","), e.Status, fmt.Sprint(e.Stale),
+	}, "|")))
+	return hex.EncodeToString(sum[:])
+}
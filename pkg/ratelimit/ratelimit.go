@@ -0,0 +1,122 @@
+// Package ratelimit provides token-bucket rate limiters, keyed by host, that
+// keep concurrent scrapers polite to the sites they fetch from.
+package ratelimit
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket holding up to burst tokens and refilling at rate
+// tokens per second. A Limiter with a rate of zero or less never blocks.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a full Limiter allowing rate requests per second with bursts of
+// up to burst requests.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait blocks until a token is available or ctx is done. Waiters are served
+// in the order they arrive.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the
+// caller must wait before using it.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by a reservation that was abandoned.
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+// PerHost hands out one Limiter per host, all with the same rate and burst.
+// It is safe for concurrent use and may be shared between sources.
+type PerHost struct {
+	rate  float64
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewPerHost returns a PerHost allowing rate requests per second to each host,
+// with bursts of up to burst requests.
+func NewPerHost(rate float64, burst int) *PerHost {
+	return &PerHost{rate: rate, burst: burst, limiters: make(map[string]*Limiter)}
+}
+
+// Wait blocks until a request to host may be made or ctx is done.
+func (p *PerHost) Wait(ctx context.Context, host string) error {
+	p.mu.Lock()
+	limiter, ok := p.limiters[host]
+	if !ok {
+		limiter = New(p.rate, p.burst)
+		p.limiters[host] = limiter
+	}
+	p.mu.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+// WaitURL is Wait for the host of rawURL. A nil PerHost never blocks.
+func (p *PerHost) WaitURL(ctx context.Context, rawURL string) error {
+	if p == nil {
+		return ctx.Err()
+	}
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return p.Wait(ctx, host)
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	l := New(2, 2)
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	// The bucket starts full, so the burst is free.
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(start); d != 0 {
+			t.Fatalf("Reservation %d waited %v, want 0", i, d)
+		}
+	}
+
+	// Further requests queue up at the refill rate.
+	if d := l.reserve(start); d != 500*time.Millisecond {
+		t.Errorf("Third reservation waited %v, want 500ms", d)
+	}
+	if d := l.reserve(start); d != time.Second {
+		t.Errorf("Fourth reservation waited %v, want 1s", d)
+	}
+
+	// Time passing pays back the debt but never overfills the bucket.
+	if d := l.reserve(start.Add(10 * time.Second)); d != 0 {
+		t.Errorf("Reservation after refill waited %v, want 0", d)
+	}
+	if l.tokens != 1 {
+		t.Errorf("Tokens = %v, want 1", l.tokens)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := New(0, 1)
+	for i := 0; i < 100; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	l := New(0.01, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("First Wait failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if l.tokens < 0 || l.tokens > 0.01 {
+		t.Errorf("Cancelled reservation was not returned: tokens = %v", l.tokens)
+	}
+}
+
+func TestPerHostSeparatesHosts(t *testing.T) {
+	p := NewPerHost(0.01, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := p.WaitURL(ctx, "https://entryboss.cc/calendar/a"); err != nil {
+		t.Fatalf("First host Wait failed: %v", err)
+	}
+	if err := p.WaitURL(ctx, "https://www.buncheur.com/events"); err != nil {
+		t.Fatalf("Second host should have its own bucket: %v", err)
+	}
+	if len(p.limiters) != 2 {
+		t.Errorf("Expected 2 limiters, got %d", len(p.limiters))
+	}
+}
+
+func TestNilPerHost(t *testing.T) {
+	var p *PerHost
+	if err := p.WaitURL(context.Background(), "https://entryboss.cc/"); err != nil {
+		t.Errorf("Nil PerHost should not block: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package retry retries failed HTTP fetches with jittered exponential backoff,
+// honouring Retry-After, and classifies failures as permanent or transient.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy describes how a failed fetch is retried.
+type Policy struct {
+	// Retries is the number of attempts made after the first one fails.
+	Retries int
+
+	// BaseDelay is the backoff before the first retry; it doubles for each
+	// retry after that, with jitter.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not
+	// waited for; the fetch fails instead.
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy retries twice, after roughly one and two seconds.
+func DefaultPolicy() Policy {
+	return Policy{Retries: 2, BaseDelay: 1 * time.Second, MaxDelay: 30 * time.Second}
+}
+
+// StatusError reports a response other than 200 OK.
+type StatusError struct {
+	StatusCode int
+
+	// RetryAfter is the delay requested by a Retry-After header on a 429 or
+	// 503 response, or zero.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("non-200 status code: %d", e.StatusCode)
+}
+
+// CheckResponse returns a *StatusError if resp is not 200 OK.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	err := &StatusError{StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		err.RetryAfter = ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return err
+}
+
+// ParseRetryAfter reads a Retry-After header given either as delay-seconds or
+// as an HTTP date. It returns zero if the header is missing or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Permanent reports whether err will not go away by retrying: a 4xx response
+// other than 408 Request Timeout, 425 Too Early or 429 Too Many Requests.
+// Network errors and 5xx responses are transient.
+func Permanent(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	}
+	return statusErr.StatusCode >= 400 && statusErr.StatusCode < 500
+}
+
+// Do calls fetch until it succeeds, fails permanently, the retries are used up
+// or ctx is done. The last error is returned.
+func (p Policy) Do(ctx context.Context, fetch func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fetch()
+		if err == nil || ctx.Err() != nil || Permanent(err) || attempt >= p.Retries {
+			return err
+		}
+
+		delay, ok := p.backoff(attempt, err)
+		if !ok {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff returns how long to wait before retrying after the given attempt
+// (counting from zero) failed with err. It reports false if the server asked
+// for a longer wait than MaxDelay.
+func (p Policy) backoff(attempt int, err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if p.MaxDelay > 0 && statusErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return statusErr.RetryAfter, true
+	}
+
+	delay := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0, true
+	}
+
+	// Equal jitter: wait at least half the delay so retries stay spread out
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)), true
+}
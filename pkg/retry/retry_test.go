@@ -0,0 +1,148 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	p := Policy{Retries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &StatusError{StatusCode: http.StatusBadGateway}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestDoStopsOnPermanentError(t *testing.T) {
+	p := Policy{Retries: 3, BaseDelay: time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return &StatusError{StatusCode: http.StatusNotFound}
+	})
+
+	if !Permanent(err) {
+		t.Errorf("Expected a permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestDoGivesUpAfterRetries(t *testing.T) {
+	p := Policy{Retries: 2, BaseDelay: time.Millisecond}
+
+	calls := 0
+	netErr := errors.New("connection reset")
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return netErr
+	})
+
+	if err != netErr {
+		t.Errorf("Expected last error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestDoRetryAfterBeyondMaxDelay(t *testing.T) {
+	p := Policy{Retries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+	calls := 0
+	p.Do(context.Background(), func() error {
+		calls++
+		return &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}
+	})
+
+	if calls != 1 {
+		t.Errorf("Expected no retry when Retry-After exceeds MaxDelay, got %d calls", calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	testCases := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < 20; i++ {
+			d, ok := p.backoff(tc.attempt, errors.New("timeout"))
+			if !ok || d < tc.min || d > tc.max {
+				t.Fatalf("backoff(%d) = %v, %v; want between %v and %v", tc.attempt, d, ok, tc.min, tc.max)
+			}
+		}
+	}
+
+	retryAfter := &StatusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 3 * time.Second}
+	if d, ok := (Policy{MaxDelay: time.Minute}).backoff(0, retryAfter); !ok || d != 3*time.Second {
+		t.Errorf("backoff with Retry-After = %v, %v; want 3s", d, ok)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"Tue, 01 Jul 2025 12:00:30 GMT", 30 * time.Second},
+		{"Tue, 01 Jul 2025 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := ParseRetryAfter(tc.value, now); got != tc.expected {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", tc.value, got, tc.expected)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	testCases := []struct {
+		err       error
+		permanent bool
+	}{
+		{&StatusError{StatusCode: http.StatusNotFound}, true},
+		{&StatusError{StatusCode: http.StatusGone}, true},
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, false},
+		{&StatusError{StatusCode: http.StatusRequestTimeout}, false},
+		{&StatusError{StatusCode: http.StatusServiceUnavailable}, false},
+		{errors.New("dial tcp: connection refused"), false},
+	}
+
+	for _, tc := range testCases {
+		if got := Permanent(tc.err); got != tc.permanent {
+			t.Errorf("Permanent(%v) = %v, want %v", tc.err, got, tc.permanent)
+		}
+	}
+}
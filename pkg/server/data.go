@@ -0,0 +1,146 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/ics"
+)
+
+// data holds the clubs and events read from a Store, and reloads them when
+// the files change on disk, e.g. after an update command has run.
+type data struct {
+	store *calendar.Store
+
+	// sequencesPath is the export-ics sequences file, if any, so dynamic
+	// calendars carry the same SEQUENCE and DTSTAMP as the static ones.
+	sequencesPath string
+
+	mu      sync.Mutex
+	stamps  map[string]fileStamp
+	current snapshot
+	lastErr error
+}
+
+// snapshot is one version of the data files. Its slices must not be modified.
+type snapshot struct {
+	clubs     []calendar.Club
+	events    []calendar.Event // every state, sorted by date
+	sequences *ics.Sequences   // nil without a sequences file
+	loaded    time.Time        // when the files last changed
+}
+
+// fileStamp identifies a version of a file well enough to notice rewrites.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newData(store *calendar.Store, sequencesPath string) *data {
+	return &data{store: store, sequencesPath: sequencesPath}
+}
+
+// snapshot returns the current data, reloading it first if any file changed.
+// If a reload fails, the previous data is kept and the error returned
+// alongside it.
+func (d *data) snapshot() (snapshot, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stamps := d.stat()
+	if d.stamps != nil && equalStamps(stamps, d.stamps) {
+		return d.current, d.lastErr
+	}
+
+	current, err := d.load()
+	if err != nil {
+		// Often a file caught half-written; try again on the next request
+		d.lastErr = err
+		return d.current, err
+	}
+
+	current.loaded = latest(stamps)
+	d.stamps, d.current, d.lastErr = stamps, current, nil
+	return d.current, nil
+}
+
+func (d *data) load() (snapshot, error) {
+	clubs, err := d.store.LoadClubs()
+	if errors.Is(err, fs.ErrNotExist) {
+		clubs, err = nil, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var events []calendar.Event
+	for _, state := range calendar.States {
+		stateEvents, err := d.store.LoadEvents(state)
+		if err != nil {
+			return snapshot{}, err
+		}
+		events = append(events, stateEvents...)
+	}
+	calendar.SortClubs(clubs)
+	calendar.SortEvents(events)
+
+	var sequences *ics.Sequences
+	if d.sequencesPath != "" {
+		if sequences, err = ics.OpenSequences(d.sequencesPath); err != nil {
+			return snapshot{}, err
+		}
+	}
+	return snapshot{clubs: clubs, events: events, sequences: sequences}, nil
+}
+
+// stat returns the stamp of every data file; missing files are left out.
+func (d *data) stat() map[string]fileStamp {
+	names := []string{calendar.ClubsFile}
+	for _, state := range calendar.States {
+		names = append(names, calendar.EventsFile(state))
+	}
+
+	paths := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		paths = append(paths, d.store.Path(name))
+	}
+	if d.sequencesPath != "" {
+		paths = append(paths, d.sequencesPath)
+	}
+
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps
+}
+
+func equalStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, stamp := range a {
+		if other, ok := b[name]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return false
+		}
+	}
+	return true
+}
+
+func latest(stamps map[string]fileStamp) time.Time {
+	var t time.Time
+	for _, stamp := range stamps {
+		if stamp.modTime.After(t) {
+			t = stamp.modTime
+		}
+	}
+	return t
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// eventQuery is a parsed /api/events request. Filters that take a list
+// accept comma-separated or repeated values and match any of them.
+type eventQuery struct {
+	states      set
+	clubs       set
+	sources     set
+	categories  set
+	disciplines set
+	types       set
+	from, to    string // "2006-01-02", inclusive
+	text        []string
+	sort        string
+	desc        bool
+	page        int
+	limit       int
+}
+
+// eventSorts are the values accepted by the sort parameter, optionally
+// prefixed with "-" for descending order.
+var eventSorts = map[string]func(a, b calendar.Event) bool{
+	"date":  func(a, b calendar.Event) bool { return a.EventDate < b.EventDate },
+	"name":  func(a, b calendar.Event) bool { return strings.ToLower(a.EventName) < strings.ToLower(b.EventName) },
+	"club":  func(a, b calendar.Event) bool { return strings.ToLower(a.ClubName) < strings.ToLower(b.ClubName) },
+	"state": func(a, b calendar.Event) bool { return a.State < b.State },
+}
+
+// parseEventQuery reads the filters, sort and page of an /api/events request.
+func parseEventQuery(v url.Values) (*eventQuery, error) {
+	q, err := parseEventFilters(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if s := v.Get("sort"); s != "" {
+		q.desc = strings.HasPrefix(s, "-")
+		q.sort = strings.TrimPrefix(s, "-")
+		if eventSorts[q.sort] == nil {
+			return nil, fmt.Errorf("invalid sort %q: use date, name, club or state, with - for descending", s)
+		}
+	}
+
+	if q.page, q.limit, err = parsePage(v); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parseEventFilters reads only the parameters that select events, for
+// endpoints such as calendar.ics that neither sort nor paginate.
+func parseEventFilters(v url.Values) (*eventQuery, error) {
+	q := &eventQuery{
+		states:      parseSet(v["state"], strings.ToUpper),
+		clubs:       parseSet(v["club"], strings.ToLower),
+		sources:     parseSet(v["source"], strings.ToLower),
+		categories:  parseSet(v["category"], strings.ToLower),
+		disciplines: parseSet(v["discipline"], strings.ToLower),
+		types:       parseSet(v["type"], strings.ToLower),
+		text:        strings.Fields(strings.ToLower(v.Get("q"))),
+		sort:        "date",
+	}
+
+	var err error
+	if q.from, err = parseDay(v.Get("from")); err != nil {
+		return nil, fmt.Errorf("invalid from: %w", err)
+	}
+	if q.to, err = parseDay(v.Get("to")); err != nil {
+		return nil, fmt.Errorf("invalid to: %w", err)
+	}
+	return q, nil
+}
+
+func (q *eventQuery) match(e calendar.Event) bool {
+	if !q.states.has(e.State) || !q.categories.has(strings.ToLower(e.Category)) || !q.disciplines.has(e.Discipline) || !q.types.has(e.Type) {
+		return false
+	}
+	// An event listed by several sources matches any of them
+	listed := false
+	for _, source := range e.Sources() {
+		listed = listed || q.sources.has(strings.ToLower(source))
+	}
+	if !listed {
+		return false
+	}
+	// Clubs may be given by name or by the slug used in calendar file names
+	if !q.clubs.has(strings.ToLower(e.ClubName)) && !q.clubs.has(calendar.Slug(e.ClubName)) {
+		return false
+	}
+
+	// An event matches a date range it overlaps, so multi-day events in
+	// progress are included
+	if q.from != "" && e.LastDate() < q.from {
+		return false
+	}
+	if q.to != "" && (len(e.EventDate) < 10 || e.EventDate[:10] > q.to) {
+		return false
+	}
+
+	if len(q.text) > 0 {
+		haystack := strings.ToLower(strings.Join([]string{e.EventName, e.ClubName, e.Venue, e.Category, e.State}, " "))
+		for _, word := range q.text {
+			if !strings.Contains(haystack, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// apply filters, sorts and pages events. It returns the page and the number
+// of matching events.
+func (q *eventQuery) apply(events []calendar.Event) ([]calendar.Event, int) {
+	matched := q.filter(events)
+	return paginate(matched, q.page, q.limit), len(matched)
+}
+
+// filter returns the matching events, sorted.
+func (q *eventQuery) filter(events []calendar.Event) []calendar.Event {
+	matched := make([]calendar.Event, 0)
+	for _, e := range events {
+		if q.match(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	less := eventSorts[q.sort]
+	sort.SliceStable(matched, func(i, j int) bool {
+		if q.desc {
+			return less(matched[j], matched[i])
+		}
+		return less(matched[i], matched[j])
+	})
+	return matched
+}
+
+// clubQuery is a parsed /api/clubs request.
+type clubQuery struct {
+	states  set
+	sources set
+	text    []string
+	page    int
+	limit   int
+}
+
+func parseClubQuery(v url.Values) (*clubQuery, error) {
+	q := &clubQuery{
+		states:  parseSet(v["state"], strings.ToUpper),
+		sources: parseSet(v["source"], strings.ToLower),
+		text:    strings.Fields(strings.ToLower(v.Get("q"))),
+	}
+	var err error
+	if q.page, q.limit, err = parsePage(v); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *clubQuery) apply(clubs []calendar.Club) ([]calendar.Club, int) {
+	matched := make([]calendar.Club, 0)
+	for _, c := range clubs {
+		if !q.states.has(c.State) || !q.sources.has(strings.ToLower(c.Source)) {
+			continue
+		}
+		name := strings.ToLower(c.ClubName)
+		found := true
+		for _, word := range q.text {
+			if !strings.Contains(name, word) {
+				found = false
+				break
+			}
+		}
+		if found {
+			matched = append(matched, c)
+		}
+	}
+	return paginate(matched, q.page, q.limit), len(matched)
+}
+
+// set is a filter; an empty set matches everything.
+type set map[string]bool
+
+func parseSet(values []string, normalise func(string) string) set {
+	s := make(set)
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				s[normalise(item)] = true
+			}
+		}
+	}
+	return s
+}
+
+func (s set) has(value string) bool {
+	return len(s) == 0 || s[value]
+}
+
+func parseDay(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return "", fmt.Errorf("%q is not a date like 2025-07-05", value)
+	}
+	return value, nil
+}
+
+// parsePage reads page (from 1) and limit (up to maxLimit).
+func parsePage(v url.Values) (page, limit int, err error) {
+	page, limit = 1, defaultLimit
+	if s := v.Get("page"); s != "" {
+		if page, err = strconv.Atoi(s); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("invalid page %q", s)
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, fmt.Errorf("invalid limit %q: must be 1 to %d", s, maxLimit)
+		}
+	}
+	// Beyond this the offset of the page would overflow
+	if page > math.MaxInt/limit {
+		return 0, 0, fmt.Errorf("invalid page %d: must be at most %d", page, math.MaxInt/limit)
+	}
+	return page, limit, nil
+}
+
+func paginate[T any](items []T, page, limit int) []T {
+	// Checked before multiplying, which could overflow
+	if page-1 > len(items)/limit {
+		return []T{}
+	}
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []T{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
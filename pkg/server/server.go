@@ -0,0 +1,240 @@
+// Package server serves the website together with a JSON API over the same
+// clubs.json and events-<state>.json files the site reads.
+package server
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"racecalendar/pkg/calendar"
+	"racecalendar/pkg/ics"
+)
+
+// Server serves the static site from SiteDir and the API from Store.
+//
+//	GET /api/events  filters: state, club, source, category, discipline,
+//	                 type, from, to (dates as 2006-01-02), q (text search);
+//	                 sort: date, name, club or state, "-" for descending;
+//	                 page, limit
+//	GET /api/clubs   filters: state, source, q; page, limit
+//	GET /calendar.ics  an iCalendar feed of the events matching the
+//	                 /api/events filters; clubs, states, categories,
+//	                 disciplines and types may be used for club, state,
+//	                 category, discipline and type
+type Server struct {
+	SiteDir string
+	Log     calendar.Logger
+
+	data *data
+}
+
+// New returns a server for the data in store and the site in siteDir.
+// Calendars reuse the event sequences written by export-ics to its default
+// directory in the site, if there are any.
+func New(store *calendar.Store, siteDir string) *Server {
+	sequences := filepath.Join(siteDir, ics.DefaultDir, ics.SequencesFile)
+	return &Server{SiteDir: siteDir, data: newData(store, sequences)}
+}
+
+// Handler returns the HTTP handler for the site and API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/events", s.handleEvents)
+	mux.HandleFunc("/api/clubs", s.handleClubs)
+	mux.HandleFunc("/calendar.ics", s.handleCalendar)
+	mux.Handle("/api/", http.NotFoundHandler())
+	mux.Handle("/", hideDotFiles(http.FileServer(http.Dir(s.SiteDir))))
+	return s.logRequests(mux)
+}
+
+type eventsResponse struct {
+	Events []calendar.Event `json:"events"`
+	Total  int              `json:"total"`
+	Page   int              `json:"page"`
+	Limit  int              `json:"limit"`
+}
+
+type clubsResponse struct {
+	Clubs []calendar.Club `json:"clubs"`
+	Total int             `json:"total"`
+	Page  int             `json:"page"`
+	Limit int             `json:"limit"`
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+	q, err := parseEventQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, ok := s.snapshot(w)
+	if !ok {
+		return
+	}
+	page, total := q.apply(data.events)
+	writeJSON(w, r, data.loaded, eventsResponse{Events: page, Total: total, Page: q.page, Limit: q.limit})
+}
+
+func (s *Server) handleClubs(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+	q, err := parseClubQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, ok := s.snapshot(w)
+	if !ok {
+		return
+	}
+	page, total := q.apply(data.clubs)
+	writeJSON(w, r, data.loaded, clubsResponse{Clubs: page, Total: total, Page: q.page, Limit: q.limit})
+}
+
+// calendarParams maps the plural parameters of /calendar.ics, which read
+// better in a subscription URL, to the /api/events filters.
+var calendarParams = map[string]string{
+	"clubs":       "club",
+	"states":      "state",
+	"sources":     "source",
+	"categories":  "category",
+	"disciplines": "discipline",
+	"types":       "type",
+}
+
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+	values := r.URL.Query()
+	for plural, singular := range calendarParams {
+		values[singular] = append(values[singular], values[plural]...)
+	}
+	q, err := parseEventFilters(values)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, ok := s.snapshot(w)
+	if !ok {
+		return
+	}
+
+	// Events without a recorded sequence are stamped with the data's
+	// modification time, so the output only changes when the data does
+	var buf bytes.Buffer
+	cal := ics.Calendar{Name: calendarName(q), Events: q.filter(data.events)}
+	if err := ics.Encode(&buf, cal, data.sequences, data.loaded); err != nil {
+		calendar.Logf(s.Log, "Failed to encode calendar: %v\n", err)
+		writeError(w, http.StatusInternalServerError, "calendar could not be built")
+		return
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	// ServeContent answers If-None-Match and If-Modified-Since with 304
+	http.ServeContent(w, r, "calendar.ics", data.loaded, bytes.NewReader(buf.Bytes()))
+}
+
+// calendarName describes the filters, e.g. "Racing Calendar: NSW, ACT".
+func calendarName(q *eventQuery) string {
+	var parts []string
+	for _, filter := range []set{q.states, q.categories, q.clubs} {
+		if len(filter) > 0 && len(filter) <= 3 {
+			values := make([]string, 0, len(filter))
+			for value := range filter {
+				values = append(values, value)
+			}
+			sort.Strings(values)
+			parts = append(parts, strings.Join(values, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "Racing Calendar"
+	}
+	return "Racing Calendar: " + strings.Join(parts, "; ")
+}
+
+// snapshot returns the current data. If the files cannot be read and there
+// is no earlier copy to fall back on, it writes a 500 and ok is false.
+func (s *Server) snapshot(w http.ResponseWriter) (snapshot, bool) {
+	data, err := s.data.snapshot()
+	if err != nil {
+		calendar.Logf(s.Log, "Failed to reload data: %v\n", err)
+		if data.loaded.IsZero() {
+			writeError(w, http.StatusInternalServerError, "data files could not be read")
+			return snapshot{}, false
+		}
+	}
+	return data, true
+}
+
+func allowRead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, modified time.Time, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// hideDotFiles keeps .git, .github, .cache and the like out of the site.
+func hideDotFiles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, part := range strings.Split(path.Clean(r.URL.Path), "/") {
+			if strings.HasPrefix(part, ".") {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	if s.Log == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		calendar.Logf(s.Log, "%s %s (%s)\n", r.Method, r.URL.RequestURI(), time.Since(start).Round(time.Millisecond))
+	})
+}
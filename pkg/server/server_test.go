@@ -0,0 +1,266 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"racecalendar/pkg/calendar"
+)
+
+func newTestServer(t *testing.T) (*calendar.Store, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	store := calendar.NewStore(dir)
+
+	if err := store.SaveClubs([]calendar.Club{
+		{ClubName: "Northern Combine", ClubURL: "https://entryboss.cc/calendar/nc", State: "VIC", Source: "EntryBoss"},
+		{ClubName: "Manly Warringah CC", ClubURL: "https://www.buncheur.com/mwcc", State: "NSW", Source: "Buncheur"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveEvents("VIC", []calendar.Event{
+		{EventName: "Winter Criterium", EventDate: "2025-07-05T00:00:00Z", ClubName: "Northern Combine", State: "VIC", Source: "EntryBoss", Category: "Criterium", Discipline: "criterium"},
+		{EventName: "Winter Tour", EventDate: "2025-06-28T00:00:00Z", EndDate: "2025-07-02T00:00:00Z", ClubName: "Northern Combine", State: "VIC", Source: "EntryBoss", Venue: "Kinglake", Discipline: "road"},
+		{EventName: "Spring Road Race", EventDate: "2025-09-06T00:00:00Z", ClubName: "Northern Combine", State: "VIC", Source: "EntryBoss", Category: "Road Race", Discipline: "road"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveEvents("NSW", []calendar.Event{
+		{EventName: "Friday Night HART", EventDate: "2025-07-04T00:00:00Z", ClubName: "Manly Warringah CC", State: "NSW", Source: "Buncheur", Category: "Criterium", Type: "training"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(New(store, dir).Handler())
+	t.Cleanup(ts.Close)
+	return store, ts
+}
+
+func getEvents(t *testing.T, ts *httptest.Server, query string) eventsResponse {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/events?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/events?%s: status %d", query, resp.StatusCode)
+	}
+	var body eventsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func names(events []calendar.Event) []string {
+	var names []string
+	for _, e := range events {
+		names = append(names, e.EventName)
+	}
+	return names
+}
+
+func TestEventsAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Winter Tour", "Friday Night HART", "Winter Criterium", "Spring Road Race"}},
+		{"state=vic", []string{"Winter Tour", "Winter Criterium", "Spring Road Race"}},
+		{"state=VIC,NSW&category=criterium", []string{"Friday Night HART", "Winter Criterium"}},
+		{"source=buncheur", []string{"Friday Night HART"}},
+		{"discipline=road", []string{"Winter Tour", "Spring Road Race"}},
+		{"type=training", []string{"Friday Night HART"}},
+		{"club=northern+combine&sort=-date", []string{"Spring Road Race", "Winter Criterium", "Winter Tour"}},
+		// The tour overlaps the range though it started before it
+		{"from=2025-07-01&to=2025-07-04", []string{"Winter Tour", "Friday Night HART"}},
+		{"q=winter+kinglake", []string{"Winter Tour"}},
+		{"sort=name&limit=2&page=2", []string{"Winter Criterium", "Winter Tour"}},
+		{"page=9", nil},
+	}
+
+	for _, tc := range testCases {
+		got := names(getEvents(t, ts, tc.query).Events)
+		if len(got) != len(tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.query, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%q: got %v, want %v", tc.query, got, tc.want)
+				break
+			}
+		}
+	}
+
+	body := getEvents(t, ts, "limit=1")
+	if body.Total != 4 || body.Page != 1 || body.Limit != 1 {
+		t.Errorf("Paging fields = total %d page %d limit %d, want 4 1 1", body.Total, body.Page, body.Limit)
+	}
+}
+
+func TestEventsAPIRejectsBadQueries(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	for _, query := range []string{"from=5/7/2025", "sort=price", "page=0", "limit=100000", "page=184467440737095518"} {
+		resp, err := http.Get(ts.URL + "/api/events?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: status %d, want 400", query, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Post(ts.URL+"/api/events", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestClubsAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/api/clubs?state=nsw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body clubsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Total != 1 || body.Clubs[0].ClubName != "Manly Warringah CC" {
+		t.Errorf("Expected Manly Warringah CC only, got %+v", body)
+	}
+}
+
+func TestReloadsChangedFiles(t *testing.T) {
+	store, ts := newTestServer(t)
+
+	if got := getEvents(t, ts, "state=NSW").Total; got != 1 {
+		t.Fatalf("Expected 1 NSW event, got %d", got)
+	}
+
+	if err := store.SaveEvents("NSW", []calendar.Event{
+		{EventName: "Friday Night HART", EventDate: "2025-07-04T00:00:00Z", State: "NSW"},
+		{EventName: "Sunday Crits", EventDate: "2025-07-06T00:00:00Z", State: "NSW"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the change is visible even on coarse file system clocks
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(store.Path(calendar.EventsFile("NSW")), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getEvents(t, ts, "state=NSW").Total; got != 2 {
+		t.Errorf("Expected 2 NSW events after the file changed, got %d", got)
+	}
+}
+
+func TestServesSiteWithoutDotFiles(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	for path, want := range map[string]int{
+		"/":                http.StatusOK,
+		"/index.html":      http.StatusMovedPermanently,
+		"/.git/config":     http.StatusNotFound,
+		"/api/unknown":     http.StatusNotFound,
+		"/events-vic.json": http.StatusOK,
+	} {
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: status %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestCalendarEndpoint(t *testing.T) {
+	_, ts := newTestServer(t)
+	url := ts.URL + "/calendar.ics?states=VIC,NSW&categories=criterium&clubs=northern-combine,Manly+Warringah+CC"
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if n := strings.Count(string(body), "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Expected the two criteriums, got %d events:\n%s", n, body)
+	}
+	if !strings.Contains(string(body), "SUMMARY:Friday Night HART") || !strings.Contains(string(body), "SUMMARY:Winter Criterium") {
+		t.Errorf("Missing expected events:\n%s", body)
+	}
+
+	etag, modified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("Missing caching headers: ETag %q, Last-Modified %q", etag, modified)
+	}
+
+	for header, value := range map[string]string{"If-None-Match": etag, "If-Modified-Since": modified} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set(header, value)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("%s: status %d, want 304", header, resp.StatusCode)
+		}
+	}
+
+	// The same query gives the same calendar
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("ETag") != etag {
+		t.Errorf("ETag changed without a data change")
+	}
+
+	// Paging parameters do not apply to calendars and are ignored
+	resp, err = http.Get(url + "&page=0&limit=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != etag {
+		t.Errorf("With bad paging parameters: status %d, ETag %q, want 200 and %q", resp.StatusCode, resp.Header.Get("ETag"), etag)
+	}
+}
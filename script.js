@@ -18,6 +18,7 @@ let currentView = 'calendar';
 let isFirstTime = true;
 let hideBMXEvents = false;
 let hideMTBEvents = false;
+let racesOnly = true; // Hide training, coaching, social and other non-race listings
 let selectedState = 'VIC'; // Default state
 let statePreferences = {}; // Store preferences for each state
 let isDarkMode = false;
@@ -104,7 +105,7 @@ let calendarViewBtn, listViewBtn, clubSearchInput, clubDropdown;
 let selectedClubsContainer, calendarView, listView, calendarGrid, eventsList;
 let loadingElement, errorElement, onboardingBanner;
 let clubListPanel, clubListContainer;
-let hideBMXCheckbox, hideMTBCheckbox, darkModeToggle;
+let hideBMXCheckbox, hideMTBCheckbox, racesOnlyCheckbox, darkModeToggle;
 
 // Mobile detection
 function isMobileDevice() {
@@ -185,6 +186,7 @@ function initializeElements() {
     clubListContainer = document.getElementById('club-list-container');
     hideBMXCheckbox = document.getElementById('hide-bmx-checkbox');
     hideMTBCheckbox = document.getElementById('hide-mtb-checkbox');
+    racesOnlyCheckbox = document.getElementById('races-only-checkbox');
     darkModeToggle = document.getElementById('dark-mode-toggle');
     
     // Check for missing critical elements
@@ -241,6 +243,14 @@ function setupEventListeners() {
             updateDisplay();
         });
     }
+    
+    if (racesOnlyCheckbox) {
+        racesOnlyCheckbox.addEventListener('change', (e) => {
+            racesOnly = e.target.checked;
+            saveState();
+            updateDisplay();
+        });
+    }
 }
 
 // State Management
@@ -294,12 +304,15 @@ function loadStateSpecificPreferences() {
     const prefs = statePreferences[selectedState] || {
         selectedClubs: [],
         hideBMXEvents: false,
-        hideMTBEvents: false
+        hideMTBEvents: false,
+        racesOnly: true
     };
     
     selectedClubs = new Set(prefs.selectedClubs);
     hideBMXEvents = prefs.hideBMXEvents;
     hideMTBEvents = prefs.hideMTBEvents;
+    // Preferences saved before event types existed default to races only
+    racesOnly = prefs.racesOnly !== false;
     
     // Update checkbox states if elements exist
     if (hideBMXCheckbox) {
@@ -308,6 +321,9 @@ function loadStateSpecificPreferences() {
     if (hideMTBCheckbox) {
         hideMTBCheckbox.checked = hideMTBEvents;
     }
+    if (racesOnlyCheckbox) {
+        racesOnlyCheckbox.checked = racesOnly;
+    }
 }
 
 function saveState() {
@@ -315,7 +331,8 @@ function saveState() {
     statePreferences[selectedState] = {
         selectedClubs: [...selectedClubs],
         hideBMXEvents: hideBMXEvents,
-        hideMTBEvents: hideMTBEvents
+        hideMTBEvents: hideMTBEvents,
+        racesOnly: racesOnly
     };
     
     saveToStorage('statePreferences', JSON.stringify(statePreferences));
@@ -860,6 +877,11 @@ function getFilteredEvents() {
         filteredEvents = filteredEvents.filter(event => selectedClubs.has(event.clubName));
     }
     
+    // Apply race filter if enabled; events without a type are races
+    if (racesOnly) {
+        filteredEvents = filteredEvents.filter(event => !event.type || event.type === 'race');
+    }
+    
     // Apply BMX filter if enabled
     if (hideBMXEvents) {
         filteredEvents = filteredEvents.filter(event => 
@@ -900,7 +922,7 @@ function renderCalendar(events) {
     // Calculate weeks needed to show all events
     let weeksToShow = 12; // Default minimum
     const lastEventDate = events.reduce((maxDate, event) => {
-        const eventDate = new Date(event.eventDate);
+        const eventDate = parseEventDate(event.eventDate);
         return eventDate > maxDate ? eventDate : maxDate;
     }, new Date(0));
 
@@ -985,7 +1007,7 @@ function createCalendarDay(date, events) {
     
     // Find events for this day
     const dayEvents = events.filter(event => {
-        const eventDate = new Date(event.eventDate);
+        const eventDate = parseEventDate(event.eventDate);
         return eventDate.toDateString() === date.toDateString();
     });
     
@@ -1022,7 +1044,7 @@ function createCalendarDay(date, events) {
 function groupEventsByDate(events) {
     const grouped = new Map();
     events.forEach(event => {
-        const eventDate = new Date(event.eventDate);
+        const eventDate = parseEventDate(event.eventDate);
         const dateKey = eventDate.toDateString(); // e.g., "Wed Jul 09 2025"
         if (!grouped.has(dateKey)) {
             grouped.set(dateKey, {
@@ -1174,7 +1196,9 @@ function createEventListItem(event) {
     
     const eventDate = document.createElement('span');
     eventDate.className = 'font-medium';
-    eventDate.textContent = formatDate(event.eventDate);
+    eventDate.textContent = event.endDate
+        ? `${formatDate(event.eventDate)} – ${formatDate(event.endDate)}`
+        : formatDate(event.eventDate);
     
     const eventClub = document.createElement('span');
     eventClub.className = 'event-club-tag px-3 py-1 rounded-full text-sm';
@@ -1217,8 +1241,17 @@ function dismissOnboarding() {
 }
 
 // Utility Functions
+
+// eventDate holds the event's local calendar date ("2025-07-05T00:00:00Z"), so
+// read the date part as a local date rather than converting from UTC, which
+// would move events to the previous day for viewers west of Greenwich.
+function parseEventDate(dateString) {
+    const [year, month, day] = dateString.slice(0, 10).split('-').map(Number);
+    return new Date(year, month - 1, day);
+}
+
 function formatDate(dateString) {
-    const date = new Date(dateString);
+    const date = parseEventDate(dateString);
     const options = { 
         weekday: 'short', 
         year: 'numeric', 
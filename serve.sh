@@ -9,8 +9,12 @@ echo "Frontend will be available at: http://localhost:8000"
 echo "Press Ctrl+C to stop the server"
 echo ""
 
-# Check if Python 3 is available
-if command -v python3 &> /dev/null; then
+# Prefer the Go server, which also provides the /api endpoints
+if command -v go &> /dev/null; then
+    echo "✅ Using racecalendar serve..."
+    echo ""
+    go run ./cmd serve --addr :8000
+elif command -v python3 &> /dev/null; then
     echo "✅ Using Python 3 development server..."
     echo ""
     python3 -m http.server 8000